@@ -24,6 +24,9 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+
+	// Resolved tells that package resolver has annotated the program.
+	Resolved bool
 }
 
 func (p *Program) TokenLiteral() string {
@@ -98,9 +101,30 @@ func (rs *ReturnStatement) TokenLiteral() string {
 	return rs.Token.Literal
 }
 
+// Scope tells where the evaluator finds the variable named by an
+// Identifier. Package resolver assigns it, along with Depth and Slot.
+type Scope int
+
+const (
+	// ScopeUnresolved variables are looked up by name in every environment
+	// enclosing the one the identifier is evaluated in.
+	ScopeUnresolved Scope = iota
+	// ScopeLocal variables are bound by a function literal Depth functions
+	// out, in the slot Slot of the environment of its call.
+	ScopeLocal
+	// ScopeGlobal variables are bound by no function literal, so they are
+	// looked up by name from the environment Depth functions out, the one
+	// the program runs in.
+	ScopeGlobal
+)
+
 type Identifier struct {
 	Token token.Token
 	Value string
+
+	Scope Scope
+	Depth int
+	Slot  int
 }
 
 func (i *Identifier) expressionNode() {
@@ -148,6 +172,21 @@ func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode() {}
+
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 type PrefixExpression struct {
 	Token    token.Token
 	Operator string
@@ -269,6 +308,15 @@ type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
 	Body       *BlockStatement
+
+	// Locals names the slots of the environments of the calls of the
+	// function, once resolved: its parameters, then the names bound by the
+	// let statements of its body.
+	Locals []string
+	// Leaf tells, once resolved, that no function literal appears in the
+	// body, so that nothing refers to the environment of a call once it has
+	// returned.
+	Leaf bool
 }
 
 func (fl FunctionLiteral) TokenLiteral() string {
@@ -300,6 +348,10 @@ type CallExpression struct {
 	Token     token.Token
 	Function  Expression
 	Arguments []Expression
+
+	// Tail tells, once resolved, that the call is in tail position of a
+	// function body, see package resolver.
+	Tail bool
 }
 
 func (ce CallExpression) TokenLiteral() string {
@@ -340,3 +392,115 @@ func (sl StringLiteral) String() string {
 }
 
 func (sl StringLiteral) expressionNode() {}
+
+type ImportExpression struct {
+	Token token.Token
+	Path  *StringLiteral
+}
+
+func (ie *ImportExpression) expressionNode() {}
+
+func (ie *ImportExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+
+func (ie *ImportExpression) String() string {
+	return ie.TokenLiteral() + " \"" + ie.Path.Value + "\""
+}
+
+type MemberExpression struct {
+	Token    token.Token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
+type ArrayLiteral struct {
+	Token    token.Token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+
+func (al *ArrayLiteral) TokenLiteral() string {
+	return al.Token.Literal
+}
+
+func (al *ArrayLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+type IndexExpression struct {
+	Token token.Token
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode() {}
+
+func (ie *IndexExpression) TokenLiteral() string {
+	return ie.Token.Literal
+}
+
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+type HashLiteral struct {
+	Token token.Token
+	Pairs []HashPair
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode() {}
+
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -36,3 +36,37 @@ func TestProgram_String(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestInspect(t *testing.T) {
+	// if (x) { -y } with a let statement missing its value
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{
+				Expression: &IfExpression{
+					Condition: &Identifier{Value: "x"},
+					Consequence: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{
+								Expression: &PrefixExpression{Operator: "-", Right: &Identifier{Value: "y"}},
+							},
+						},
+					},
+				},
+			},
+			&LetStatement{Name: &Identifier{Value: "z"}},
+		},
+	}
+
+	var got []string
+	Inspect(program, func(n Node) bool {
+		if id, ok := n.(*Identifier); ok {
+			got = append(got, id.Value)
+		}
+		_, isBlock := n.(*BlockStatement)
+		return !isBlock
+	})
+
+	if len(got) != 2 || got[0] != "x" || got[1] != "z" {
+		t.Errorf("unexpected identifiers visited. got=%q", got)
+	}
+}
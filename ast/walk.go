@@ -0,0 +1,75 @@
+package ast
+
+import "reflect"
+
+// Inspect traverses the tree rooted at node in depth-first order, calling f
+// for each node before its children. If f returns false, the children of the
+// node are skipped. Nil nodes, which trees with parse errors may contain, are
+// not visited.
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *LetStatement:
+		Inspect(n.Name, f)
+		Inspect(n.Value, f)
+	case *ReturnStatement:
+		Inspect(n.ReturnValue, f)
+	case *ExpressionStatement:
+		Inspect(n.Expression, f)
+	case *BlockStatement:
+		for _, s := range n.Statements {
+			Inspect(s, f)
+		}
+	case *PrefixExpression:
+		Inspect(n.Right, f)
+	case *InfixExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Right, f)
+	case *IfExpression:
+		Inspect(n.Condition, f)
+		Inspect(n.Consequence, f)
+		Inspect(n.Alternative, f)
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Inspect(p, f)
+		}
+		Inspect(n.Body, f)
+	case *CallExpression:
+		Inspect(n.Function, f)
+		for _, a := range n.Arguments {
+			Inspect(a, f)
+		}
+	case *ImportExpression:
+		Inspect(n.Path, f)
+	case *MemberExpression:
+		Inspect(n.Object, f)
+		Inspect(n.Property, f)
+	case *ArrayLiteral:
+		for _, e := range n.Elements {
+			Inspect(e, f)
+		}
+	case *IndexExpression:
+		Inspect(n.Left, f)
+		Inspect(n.Index, f)
+	case *HashLiteral:
+		for _, p := range n.Pairs {
+			Inspect(p.Key, f)
+			Inspect(p.Value, f)
+		}
+	}
+}
+
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,125 @@
+// Package astjson encodes the tokens and syntax trees of monkey programs as
+// JSON, for tools that are not written in Go, and decodes syntax trees back.
+//
+// Both documents are objects carrying the format version:
+//
+//	{"version": 1, "tokens": [TOKEN, ...]}
+//	{"version": 1, "program": NODE, "errors": [ERROR, ...]}
+//
+// A TOKEN is {"type", "literal", "pos", "end"}, where pos and end are
+// positions {"offset", "line", "column"} of the first character of the token
+// and of the character after it. A NODE is an object with the "kind" of the
+// node, which is the name of its ast type, the "token" it starts with and its
+// fields in lower camel case; absent nodes are null. ERRORs are the parse
+// errors, {"code", "message", "start", "end", "expected", "found"}.
+//
+// Fields are only added within a version; a change that could break a
+// reader increments Version.
+package astjson
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/parser"
+	"github.com/yagihash/monkey/token"
+)
+
+// Version is the version of the format written by this package.
+const Version = 1
+
+type position struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type tokenJSON struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Pos     position        `json:"pos"`
+	End     position        `json:"end"`
+}
+
+type errorJSON struct {
+	Code     parser.ErrorCode  `json:"code"`
+	Message  string            `json:"message"`
+	Start    position          `json:"start"`
+	End      position          `json:"end"`
+	Expected []token.TokenType `json:"expected"`
+	Found    tokenJSON         `json:"found"`
+}
+
+type tokensDocument struct {
+	Version int         `json:"version"`
+	Tokens  []tokenJSON `json:"tokens"`
+}
+
+type programDocument struct {
+	Version int             `json:"version"`
+	Program json.RawMessage `json:"program"`
+	Errors  []errorJSON     `json:"errors"`
+}
+
+// EncodeTokens writes the tokens read from l, up to and including EOF.
+func EncodeTokens(w io.Writer, l *lexer.Lexer) error {
+	doc := tokensDocument{Version: Version, Tokens: []tokenJSON{}}
+	for {
+		tok := l.NextToken()
+		doc.Tokens = append(doc.Tokens, fromToken(tok))
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	return write(w, doc)
+}
+
+// EncodeProgram writes program together with the errors found while parsing
+// it, which may be nil.
+func EncodeProgram(w io.Writer, program *ast.Program, errs []*parser.Error) error {
+	raw, err := json.Marshal(encodeNode(program))
+	if err != nil {
+		return err
+	}
+
+	doc := programDocument{Version: Version, Program: raw, Errors: []errorJSON{}}
+	for _, e := range errs {
+		expected := e.Expected
+		if expected == nil {
+			expected = []token.TokenType{}
+		}
+
+		doc.Errors = append(doc.Errors, errorJSON{
+			Code:     e.Code,
+			Message:  e.Message,
+			Start:    fromPosition(e.Start),
+			End:      fromPosition(e.End),
+			Expected: expected,
+			Found:    fromToken(e.Found),
+		})
+	}
+
+	return write(w, doc)
+}
+
+func write(w io.Writer, doc interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func fromPosition(p token.Position) position {
+	return position{Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
+
+func fromToken(tok token.Token) tokenJSON {
+	return tokenJSON{
+		Type:    tok.Type,
+		Literal: tok.Literal,
+		Pos:     fromPosition(tok.Pos),
+		End:     fromPosition(tok.End),
+	}
+}
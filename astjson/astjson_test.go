@@ -0,0 +1,270 @@
+package astjson
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/parser"
+)
+
+func TestEncodeTokens(t *testing.T) {
+	var buf bytes.Buffer
+	if err := EncodeTokens(&buf, lexer.New(`x == "a"`)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "version": 1,
+  "tokens": [
+    {
+      "type": "IDENT",
+      "literal": "x",
+      "pos": {
+        "offset": 0,
+        "line": 1,
+        "column": 1
+      },
+      "end": {
+        "offset": 1,
+        "line": 1,
+        "column": 2
+      }
+    },
+    {
+      "type": "==",
+      "literal": "==",
+      "pos": {
+        "offset": 2,
+        "line": 1,
+        "column": 3
+      },
+      "end": {
+        "offset": 4,
+        "line": 1,
+        "column": 5
+      }
+    },
+    {
+      "type": "STRING",
+      "literal": "a",
+      "pos": {
+        "offset": 5,
+        "line": 1,
+        "column": 6
+      },
+      "end": {
+        "offset": 8,
+        "line": 1,
+        "column": 9
+      }
+    },
+    {
+      "type": "EOF",
+      "literal": "",
+      "pos": {
+        "offset": 8,
+        "line": 1,
+        "column": 9
+      },
+      "end": {
+        "offset": 8,
+        "line": 1,
+        "column": 9
+      }
+    }
+  ]
+}
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("unexpected output\n%s", diff)
+	}
+}
+
+func TestEncodeProgram(t *testing.T) {
+	p := parser.New(lexer.New("-x; let y 1;"))
+	program := p.ParseProgram()
+
+	var buf bytes.Buffer
+	if err := EncodeProgram(&buf, program, p.Diagnostics()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "version": 1,
+  "program": {
+    "kind": "Program",
+    "statements": [
+      {
+        "expression": {
+          "kind": "PrefixExpression",
+          "operator": "-",
+          "right": {
+            "kind": "Identifier",
+            "token": {
+              "type": "IDENT",
+              "literal": "x",
+              "pos": {
+                "offset": 1,
+                "line": 1,
+                "column": 2
+              },
+              "end": {
+                "offset": 2,
+                "line": 1,
+                "column": 3
+              }
+            },
+            "value": "x"
+          },
+          "token": {
+            "type": "-",
+            "literal": "-",
+            "pos": {
+              "offset": 0,
+              "line": 1,
+              "column": 1
+            },
+            "end": {
+              "offset": 1,
+              "line": 1,
+              "column": 2
+            }
+          }
+        },
+        "kind": "ExpressionStatement",
+        "token": {
+          "type": "-",
+          "literal": "-",
+          "pos": {
+            "offset": 0,
+            "line": 1,
+            "column": 1
+          },
+          "end": {
+            "offset": 1,
+            "line": 1,
+            "column": 2
+          }
+        }
+      }
+    ]
+  },
+  "errors": [
+    {
+      "code": "unexpected-token",
+      "message": "expected next token to be =, got INT instead",
+      "start": {
+        "offset": 10,
+        "line": 1,
+        "column": 11
+      },
+      "end": {
+        "offset": 11,
+        "line": 1,
+        "column": 12
+      },
+      "expected": [
+        "="
+      ],
+      "found": {
+        "type": "INT",
+        "literal": "1",
+        "pos": {
+          "offset": 10,
+          "line": 1,
+          "column": 11
+        },
+        "end": {
+          "offset": 11,
+          "line": 1,
+          "column": 12
+        }
+      }
+    }
+  ]
+}
+`
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("unexpected output\n%s", diff)
+	}
+}
+
+func TestDecodeProgram(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		input := `
+let m = import "math.monkey";
+let f = fn(x, y) {
+  if (x < y) { return -x; } else { !true }
+};
+let h = {"a": [1, 2.5][0], 2: m.pi};
+f(1, h["a"]) * 3 == 4;
+let g = fn() { false };
+`
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parse errors: %q", p.Errors())
+		}
+
+		var buf bytes.Buffer
+		if err := EncodeProgram(&buf, program, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		decoded, err := DecodeProgram(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(program, decoded, equateEmpty); diff != "" {
+			t.Errorf("decoded program differs\n%s", diff)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  string
+		}{
+			{`{"version": 2, "program": null}`, "astjson: unsupported version 2"},
+			{`{"version": 1, "program": {"kind": "Loop"}}`, `astjson: unknown node kind "Loop"`},
+			{`{"version": 1, "program": {"kind": "Identifier"}}`, "astjson: program is Identifier, not Program"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "Identifier"}]}}`, "astjson: Identifier is not a statement"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "LetStatement", "name": {"kind": "IntegerLiteral"}, "value": {"kind": "IntegerLiteral"}}]}}`, "astjson: IntegerLiteral is not an Identifier"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "LetStatement", "value": {"kind": "IntegerLiteral"}}]}}`, "astjson: LetStatement has no name"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "LetStatement", "name": {"kind": "Identifier"}, "value": null}]}}`, "astjson: LetStatement has no value"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "expression": {"kind": "ImportExpression"}}]}}`, "astjson: ImportExpression has no path"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "expression": {"kind": "MemberExpression", "object": {"kind": "Identifier"}}}]}}`, "astjson: MemberExpression has no property"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "expression": {"kind": "InfixExpression", "right": {"kind": "IntegerLiteral"}}}]}}`, "astjson: InfixExpression has no left"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "expression": {"kind": "InfixExpression", "left": {"kind": "IntegerLiteral"}, "right": null}}]}}`, "astjson: InfixExpression has no right"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "expression": {"kind": "ArrayLiteral", "elements": [null]}}]}}`, "astjson: ArrayLiteral has a null element in elements"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "expression": {"kind": "HashLiteral", "pairs": [{"key": {"kind": "IntegerLiteral"}}]}}]}}`, "astjson: HashLiteral pair has no value"},
+			{`{"version": 1, "program": {"kind": "Program", "statements": [null]}}`, "astjson: Program has a null element in statements"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				_, err := DecodeProgram(strings.NewReader(c.input))
+				if err == nil || err.Error() != c.want {
+					t.Errorf("unexpected error. want=%q, got=%v", c.want, err)
+				}
+			})
+		}
+	})
+}
+
+// equateEmpty treats nil and empty slices and maps as equal, as the decoder
+// does not tell them apart.
+var equateEmpty = cmp.FilterValues(func(x, y interface{}) bool {
+	vx, vy := reflect.ValueOf(x), reflect.ValueOf(y)
+	switch vx.Kind() {
+	case reflect.Slice, reflect.Map:
+		return vx.Len() == 0 && vy.Len() == 0
+	}
+	return false
+}, cmp.Comparer(func(x, y interface{}) bool { return true }))
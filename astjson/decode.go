@@ -0,0 +1,322 @@
+package astjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/resolver"
+	"github.com/yagihash/monkey/token"
+)
+
+// DecodeProgram reads a document written by EncodeProgram and rebuilds the
+// program in it, resolved as ParseProgram does. The parse errors in the
+// document are ignored.
+func DecodeProgram(r io.Reader) (*ast.Program, error) {
+	var doc programDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Version != Version {
+		return nil, fmt.Errorf("astjson: unsupported version %d", doc.Version)
+	}
+
+	d := &decoder{}
+	n := d.node(doc.Program)
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	program, ok := n.(*ast.Program)
+	if !ok {
+		return nil, fmt.Errorf("astjson: program is %s, not Program", kindOf(n))
+	}
+
+	resolver.Resolve(program)
+	return program, nil
+}
+
+// decoder keeps the first error it runs into, so that the methods building a
+// node can be chained without checking every field.
+type decoder struct {
+	err error
+}
+
+func (d *decoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf("astjson: "+format, a...)
+	}
+}
+
+func (d *decoder) unmarshal(raw json.RawMessage, v interface{}) {
+	if d.err != nil || raw == nil {
+		return
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		d.fail("%s", err)
+	}
+}
+
+func (d *decoder) node(raw json.RawMessage) ast.Node {
+	var fields map[string]json.RawMessage
+	d.unmarshal(raw, &fields)
+	if d.err != nil || fields == nil {
+		return nil
+	}
+
+	var kind string
+	d.unmarshal(fields["kind"], &kind)
+
+	var tj tokenJSON
+	d.unmarshal(fields["token"], &tj)
+	tok := toToken(tj)
+
+	d.require(kind, fields, required[kind]...)
+
+	switch kind {
+	case "Program":
+		return &ast.Program{Statements: d.statements(kind, "statements", fields["statements"])}
+	case "LetStatement":
+		return &ast.LetStatement{
+			Token: tok,
+			Name:  d.identifier(fields["name"]),
+			Value: d.expression(fields["value"]),
+		}
+	case "ReturnStatement":
+		return &ast.ReturnStatement{Token: tok, ReturnValue: d.expression(fields["returnValue"])}
+	case "ExpressionStatement":
+		return &ast.ExpressionStatement{Token: tok, Expression: d.expression(fields["expression"])}
+	case "BlockStatement":
+		return &ast.BlockStatement{Token: tok, Statements: d.statements(kind, "statements", fields["statements"])}
+	case "Identifier":
+		n := &ast.Identifier{Token: tok}
+		d.unmarshal(fields["value"], &n.Value)
+		return n
+	case "IntegerLiteral":
+		n := &ast.IntegerLiteral{Token: tok}
+		d.unmarshal(fields["value"], &n.Value)
+		return n
+	case "FloatLiteral":
+		n := &ast.FloatLiteral{Token: tok}
+		d.unmarshal(fields["value"], &n.Value)
+		return n
+	case "StringLiteral":
+		n := &ast.StringLiteral{Token: tok}
+		d.unmarshal(fields["value"], &n.Value)
+		return n
+	case "Boolean":
+		n := &ast.Boolean{Token: tok}
+		d.unmarshal(fields["value"], &n.Value)
+		return n
+	case "PrefixExpression":
+		n := &ast.PrefixExpression{Token: tok, Right: d.expression(fields["right"])}
+		d.unmarshal(fields["operator"], &n.Operator)
+		return n
+	case "InfixExpression":
+		n := &ast.InfixExpression{
+			Token: tok,
+			Left:  d.expression(fields["left"]),
+			Right: d.expression(fields["right"]),
+		}
+		d.unmarshal(fields["operator"], &n.Operator)
+		return n
+	case "IfExpression":
+		return &ast.IfExpression{
+			Token:       tok,
+			Condition:   d.expression(fields["condition"]),
+			Consequence: d.block(fields["consequence"]),
+			Alternative: d.block(fields["alternative"]),
+		}
+	case "FunctionLiteral":
+		var raws []json.RawMessage
+		d.unmarshal(fields["parameters"], &raws)
+
+		n := &ast.FunctionLiteral{Token: tok, Body: d.block(fields["body"])}
+		for _, raw := range raws {
+			if isNull(raw) {
+				d.fail("%s has a null element in parameters", kind)
+			}
+			n.Parameters = append(n.Parameters, d.identifier(raw))
+		}
+		return n
+	case "CallExpression":
+		return &ast.CallExpression{
+			Token:     tok,
+			Function:  d.expression(fields["function"]),
+			Arguments: d.expressions(kind, "arguments", fields["arguments"]),
+		}
+	case "ImportExpression":
+		n := &ast.ImportExpression{Token: tok}
+		if path := d.node(fields["path"]); path != nil {
+			lit, ok := path.(*ast.StringLiteral)
+			if !ok {
+				d.fail("import path is %s, not StringLiteral", kindOf(path))
+			}
+			n.Path = lit
+		}
+		return n
+	case "MemberExpression":
+		return &ast.MemberExpression{
+			Token:    tok,
+			Object:   d.expression(fields["object"]),
+			Property: d.identifier(fields["property"]),
+		}
+	case "ArrayLiteral":
+		return &ast.ArrayLiteral{Token: tok, Elements: d.expressions(kind, "elements", fields["elements"])}
+	case "IndexExpression":
+		return &ast.IndexExpression{
+			Token: tok,
+			Left:  d.expression(fields["left"]),
+			Index: d.expression(fields["index"]),
+		}
+	case "HashLiteral":
+		var pairs []map[string]json.RawMessage
+		d.unmarshal(fields["pairs"], &pairs)
+
+		n := &ast.HashLiteral{Token: tok}
+		for _, p := range pairs {
+			d.require(kind+" pair", p, "key", "value")
+			n.Pairs = append(n.Pairs, ast.HashPair{
+				Key:   d.expression(p["key"]),
+				Value: d.expression(p["value"]),
+			})
+		}
+		return n
+	}
+
+	d.fail("unknown node kind %q", kind)
+	return nil
+}
+
+// required lists the fields of each kind of node that must not be missing or
+// null, as the evaluator expects them to be set.
+var required = map[string][]string{
+	"LetStatement":        {"name", "value"},
+	"ReturnStatement":     {"returnValue"},
+	"ExpressionStatement": {"expression"},
+	"PrefixExpression":    {"right"},
+	"InfixExpression":     {"left", "right"},
+	"IfExpression":        {"condition", "consequence"},
+	"FunctionLiteral":     {"body"},
+	"CallExpression":      {"function"},
+	"ImportExpression":    {"path"},
+	"MemberExpression":    {"object", "property"},
+	"IndexExpression":     {"left", "index"},
+}
+
+// require fails if any of the fields names of a node of kind is missing or
+// null.
+func (d *decoder) require(kind string, fields map[string]json.RawMessage, names ...string) {
+	for _, name := range names {
+		if isNull(fields[name]) {
+			d.fail("%s has no %s", kind, name)
+			return
+		}
+	}
+}
+
+func isNull(raw json.RawMessage) bool {
+	return raw == nil || string(raw) == "null"
+}
+
+func (d *decoder) statements(kind, field string, raw json.RawMessage) []ast.Statement {
+	var raws []json.RawMessage
+	d.unmarshal(raw, &raws)
+
+	list := []ast.Statement{}
+	for _, r := range raws {
+		if isNull(r) {
+			d.fail("%s has a null element in %s", kind, field)
+		}
+		n := d.node(r)
+		if n == nil {
+			continue
+		}
+
+		stmt, ok := n.(ast.Statement)
+		if !ok {
+			d.fail("%s is not a statement", kindOf(n))
+			continue
+		}
+		list = append(list, stmt)
+	}
+	return list
+}
+
+func (d *decoder) expressions(kind, field string, raw json.RawMessage) []ast.Expression {
+	var raws []json.RawMessage
+	d.unmarshal(raw, &raws)
+
+	list := []ast.Expression{}
+	for _, r := range raws {
+		if isNull(r) {
+			d.fail("%s has a null element in %s", kind, field)
+		}
+		list = append(list, d.expression(r))
+	}
+	return list
+}
+
+func (d *decoder) expression(raw json.RawMessage) ast.Expression {
+	n := d.node(raw)
+	if n == nil {
+		return nil
+	}
+
+	exp, ok := n.(ast.Expression)
+	if !ok {
+		d.fail("%s is not an expression", kindOf(n))
+		return nil
+	}
+	return exp
+}
+
+func (d *decoder) identifier(raw json.RawMessage) *ast.Identifier {
+	n := d.node(raw)
+	if n == nil {
+		return nil
+	}
+
+	ident, ok := n.(*ast.Identifier)
+	if !ok {
+		d.fail("%s is not an Identifier", kindOf(n))
+		return nil
+	}
+	return ident
+}
+
+func (d *decoder) block(raw json.RawMessage) *ast.BlockStatement {
+	n := d.node(raw)
+	if n == nil {
+		return nil
+	}
+
+	block, ok := n.(*ast.BlockStatement)
+	if !ok {
+		d.fail("%s is not a BlockStatement", kindOf(n))
+		return nil
+	}
+	return block
+}
+
+func kindOf(n ast.Node) string {
+	if n == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%T", n)[len("*ast."):]
+}
+
+func toPosition(p position) token.Position {
+	return token.Position{Offset: p.Offset, Line: p.Line, Column: p.Column}
+}
+
+func toToken(tj tokenJSON) token.Token {
+	return token.Token{
+		Type:    tj.Type,
+		Literal: tj.Literal,
+		Pos:     toPosition(tj.Pos),
+		End:     toPosition(tj.End),
+	}
+}
@@ -0,0 +1,197 @@
+package astjson
+
+import (
+	"github.com/yagihash/monkey/ast"
+)
+
+// node is the JSON form of an ast.Node. encoding/json writes its keys sorted,
+// which keeps the output stable.
+type node map[string]interface{}
+
+func newNode(kind string, tok interface{}) node {
+	return node{"kind": kind, "token": tok}
+}
+
+// encodeNode returns the JSON form of n, or nil if n is nil, including a nil
+// pointer wrapped in the interface.
+func encodeNode(n ast.Node) interface{} {
+	switch n := n.(type) {
+	case *ast.Program:
+		if n == nil {
+			return nil
+		}
+		return node{"kind": "Program", "statements": encodeStatements(n.Statements)}
+	case *ast.LetStatement:
+		if n == nil {
+			return nil
+		}
+		v := newNode("LetStatement", fromToken(n.Token))
+		v["name"] = encodeNode(n.Name)
+		v["value"] = encodeNode(n.Value)
+		return v
+	case *ast.ReturnStatement:
+		if n == nil {
+			return nil
+		}
+		v := newNode("ReturnStatement", fromToken(n.Token))
+		v["returnValue"] = encodeNode(n.ReturnValue)
+		return v
+	case *ast.ExpressionStatement:
+		if n == nil {
+			return nil
+		}
+		v := newNode("ExpressionStatement", fromToken(n.Token))
+		v["expression"] = encodeNode(n.Expression)
+		return v
+	case *ast.BlockStatement:
+		if n == nil {
+			return nil
+		}
+		v := newNode("BlockStatement", fromToken(n.Token))
+		v["statements"] = encodeStatements(n.Statements)
+		return v
+	case *ast.Identifier:
+		if n == nil {
+			return nil
+		}
+		v := newNode("Identifier", fromToken(n.Token))
+		v["value"] = n.Value
+		return v
+	case *ast.IntegerLiteral:
+		if n == nil {
+			return nil
+		}
+		v := newNode("IntegerLiteral", fromToken(n.Token))
+		v["value"] = n.Value
+		return v
+	case *ast.FloatLiteral:
+		if n == nil {
+			return nil
+		}
+		v := newNode("FloatLiteral", fromToken(n.Token))
+		v["value"] = n.Value
+		return v
+	case *ast.StringLiteral:
+		if n == nil {
+			return nil
+		}
+		v := newNode("StringLiteral", fromToken(n.Token))
+		v["value"] = n.Value
+		return v
+	case *ast.Boolean:
+		if n == nil {
+			return nil
+		}
+		v := newNode("Boolean", fromToken(n.Token))
+		v["value"] = n.Value
+		return v
+	case *ast.PrefixExpression:
+		if n == nil {
+			return nil
+		}
+		v := newNode("PrefixExpression", fromToken(n.Token))
+		v["operator"] = n.Operator
+		v["right"] = encodeNode(n.Right)
+		return v
+	case *ast.InfixExpression:
+		if n == nil {
+			return nil
+		}
+		v := newNode("InfixExpression", fromToken(n.Token))
+		v["left"] = encodeNode(n.Left)
+		v["operator"] = n.Operator
+		v["right"] = encodeNode(n.Right)
+		return v
+	case *ast.IfExpression:
+		if n == nil {
+			return nil
+		}
+		v := newNode("IfExpression", fromToken(n.Token))
+		v["condition"] = encodeNode(n.Condition)
+		v["consequence"] = encodeNode(n.Consequence)
+		v["alternative"] = encodeNode(n.Alternative)
+		return v
+	case *ast.FunctionLiteral:
+		if n == nil {
+			return nil
+		}
+		params := []interface{}{}
+		for _, p := range n.Parameters {
+			params = append(params, encodeNode(p))
+		}
+		v := newNode("FunctionLiteral", fromToken(n.Token))
+		v["parameters"] = params
+		v["body"] = encodeNode(n.Body)
+		return v
+	case *ast.CallExpression:
+		if n == nil {
+			return nil
+		}
+		v := newNode("CallExpression", fromToken(n.Token))
+		v["function"] = encodeNode(n.Function)
+		v["arguments"] = encodeExpressions(n.Arguments)
+		return v
+	case *ast.ImportExpression:
+		if n == nil {
+			return nil
+		}
+		v := newNode("ImportExpression", fromToken(n.Token))
+		v["path"] = encodeNode(n.Path)
+		return v
+	case *ast.MemberExpression:
+		if n == nil {
+			return nil
+		}
+		v := newNode("MemberExpression", fromToken(n.Token))
+		v["object"] = encodeNode(n.Object)
+		v["property"] = encodeNode(n.Property)
+		return v
+	case *ast.ArrayLiteral:
+		if n == nil {
+			return nil
+		}
+		v := newNode("ArrayLiteral", fromToken(n.Token))
+		v["elements"] = encodeExpressions(n.Elements)
+		return v
+	case *ast.IndexExpression:
+		if n == nil {
+			return nil
+		}
+		v := newNode("IndexExpression", fromToken(n.Token))
+		v["left"] = encodeNode(n.Left)
+		v["index"] = encodeNode(n.Index)
+		return v
+	case *ast.HashLiteral:
+		if n == nil {
+			return nil
+		}
+		pairs := []interface{}{}
+		for _, p := range n.Pairs {
+			pairs = append(pairs, map[string]interface{}{
+				"key":   encodeNode(p.Key),
+				"value": encodeNode(p.Value),
+			})
+		}
+		v := newNode("HashLiteral", fromToken(n.Token))
+		v["pairs"] = pairs
+		return v
+	}
+
+	return nil
+}
+
+func encodeStatements(stmts []ast.Statement) []interface{} {
+	list := []interface{}{}
+	for _, s := range stmts {
+		list = append(list, encodeNode(s))
+	}
+	return list
+}
+
+func encodeExpressions(exps []ast.Expression) []interface{} {
+	list := []interface{}{}
+	for _, e := range exps {
+		list = append(list, encodeNode(e))
+	}
+	return list
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/yagihash/monkey/coverage"
+)
+
+func coverCommand(args []string) error {
+	fs := flag.NewFlagSet("cover", flag.ExitOnError)
+	html := fs.String("html", "", "also write the source annotated with its coverage to `file`")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: monkey cover [-html file] coverage.out")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	p, err := coverage.Parse(f)
+	if err != nil {
+		return err
+	}
+
+	if err := p.WriteReport(os.Stdout); err != nil {
+		return err
+	}
+
+	if *html == "" {
+		return nil
+	}
+	return writeFile(*html, func(w io.Writer) error {
+		return p.WriteHTML(w, os.ReadFile)
+	})
+}
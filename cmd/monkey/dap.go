@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/yagihash/monkey/dap"
+)
+
+// dapCommand serves the Debug Adapter Protocol on standard input and output,
+// or to the first client connecting to the -listen address. As clients can
+// run code and read files, only loopback addresses are accepted.
+func dapCommand(args []string) error {
+	fs := flag.NewFlagSet("dap", flag.ExitOnError)
+	listen := fs.String("listen", "", "serve a client connecting to `addr`, such as 127.0.0.1:4711, instead of stdio")
+	fs.Parse(args)
+
+	if *listen == "" {
+		return dap.NewServer(os.Stdin, os.Stdout).Serve()
+	}
+
+	addr, err := net.ResolveTCPAddr("tcp", *listen)
+	if err != nil {
+		return err
+	}
+	if !addr.IP.IsLoopback() {
+		return fmt.Errorf("not a loopback address: %s", *listen)
+	}
+
+	l, err := net.ListenTCP("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	fmt.Fprintf(os.Stderr, "listening on %s\n", l.Addr())
+
+	conn, err := l.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return dap.NewServer(conn, conn).Serve()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/yagihash/monkey/debugger"
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/object"
+)
+
+func debugCommand(args []string) error {
+	fs := flag.NewFlagSet("debug", flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: monkey debug script.monkey")
+	}
+
+	name := fs.Arg(0)
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	program, err := parseScript(name, string(src))
+	if err != nil {
+		return err
+	}
+
+	// Imports are resolved relative to the script.
+	loader := evaluator.NewModuleLoader(os.DirFS(filepath.Dir(name)))
+	d := debugger.New(program, evaluator.WithModuleLoader(loader))
+
+	console := debugger.NewConsole(d, name, string(src), os.Stdin, os.Stdout)
+	return console.Run(object.NewEnvironment())
+}
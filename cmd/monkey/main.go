@@ -4,17 +4,71 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"sort"
 
 	"github.com/yagihash/monkey/repl"
 )
 
+type command struct {
+	args string
+	help string
+	run  func(args []string) error
+}
+
+var commands = map[string]command{
+	"tokens": {args: "[file]", help: "print the tokens of a program as JSON", run: tokensCommand},
+	"ast":    {args: "[file]", help: "print the syntax tree of a program as JSON", run: astCommand},
+	"debug":  {args: "script.monkey", help: "run a script in the debugger", run: debugCommand},
+	"dap":    {args: "[-listen addr]", help: "serve the Debug Adapter Protocol", run: dapCommand},
+	"run":    {args: "[-profile file] [-cover file] [-optimize] script.monkey", help: "run a script", run: runCommand},
+	"cover":  {args: "[-html file] coverage.out", help: "report the coverage written by run -cover", run: coverCommand},
+	"test":   {args: "[-run regexp] [-junit] [dir]", help: "run the tests of *_test.monkey files", run: testCommand},
+}
+
 func main() {
-	u, err := user.Current()
-	if err != nil {
-		panic(err)
+	if len(os.Args) < 2 {
+		if err := startREPL(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "monkey %s: %s\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
 	}
-	fmt.Printf("Hello %s! This is the Monkey programming language!\n",
-		u.Username)
-	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: monkey [command]")
+	fmt.Fprintln(os.Stderr, "\nWithout a command, monkey starts the REPL. The commands are:")
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(os.Stderr, "  %-24s %s\n", name+" "+cmd.args, cmd.help)
+	}
+}
+
+func startREPL() error {
+	name := "there"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	banner := fmt.Sprintf("Hello %s! This is the Monkey programming language!\n", name) +
+		"Feel free to type in commands\n"
+
+	return repl.NewSession(repl.WithBanner(banner), repl.WithModules(os.DirFS("."))).Run()
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/coverage"
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/optimize"
+	"github.com/yagihash/monkey/parser"
+	"github.com/yagihash/monkey/profiler"
+)
+
+func runCommand(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	profile := fs.String("profile", "", "write a pprof profile of the script's functions to `file`")
+	cover := fs.String("cover", "", "write the coverage of the script's statements and branches to `file`")
+	opt := fs.Bool("optimize", false, "fold constants and remove dead code before running the script")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		return errors.New("usage: monkey run [-profile file] [-cover file] [-optimize] script.monkey")
+	}
+
+	name := fs.Arg(0)
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	program, err := parseScript(name, string(src))
+	if err != nil {
+		return err
+	}
+	if *opt {
+		optimize.Optimize(program)
+	}
+
+	// Imports are resolved relative to the script.
+	dir := filepath.Dir(name)
+	opts := []evaluator.Option{evaluator.WithModuleLoader(evaluator.NewModuleLoader(os.DirFS(dir)))}
+
+	var cov *coverage.Profile
+	if *cover != "" {
+		cov = coverage.New(dir)
+		cov.Add(name, program)
+		opts = append(opts, evaluator.WithCoverage(cov))
+	}
+
+	env := object.NewEnvironment()
+
+	var result object.Object
+	if *profile == "" {
+		result = evaluator.New(opts...).Eval(program, env)
+	} else {
+		p := profiler.New(name, program, opts...)
+		result = p.Run(env)
+		if err := writeFile(*profile, p.WriteProfile); err != nil {
+			return err
+		}
+	}
+
+	if cov != nil {
+		if err := writeFile(*cover, cov.Write); err != nil {
+			return err
+		}
+	}
+
+	if errObj, ok := result.(*object.Error); ok {
+		return errors.New(errObj.Message)
+	}
+	return nil
+}
+
+// parseScript parses src, the script called name, printing its syntax errors.
+func parseScript(name, src string) (*ast.Program, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		for _, d := range p.Diagnostics() {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", name, d)
+		}
+		return nil, errors.New("program has syntax errors")
+	}
+
+	return program, nil
+}
+
+// writeFile creates the file called name and writes it with write.
+func writeFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
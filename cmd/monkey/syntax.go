@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yagihash/monkey/astjson"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/parser"
+)
+
+func tokensCommand(args []string) error {
+	_, src, err := readSource("tokens", args)
+	if err != nil {
+		return err
+	}
+
+	return astjson.EncodeTokens(os.Stdout, lexer.New(src))
+}
+
+// astCommand prints the syntax tree even if the program has errors, so that
+// tools can show them along with what could be parsed.
+func astCommand(args []string) error {
+	name, src, err := readSource("ast", args)
+	if err != nil {
+		return err
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+
+	if err := astjson.EncodeProgram(os.Stdout, program, p.Diagnostics()); err != nil {
+		return err
+	}
+
+	if len(p.Diagnostics()) != 0 {
+		for _, d := range p.Diagnostics() {
+			fmt.Fprintf(os.Stderr, "%s:%s\n", name, d)
+		}
+		return errors.New("program has syntax errors")
+	}
+
+	return nil
+}
+
+// readSource reads the program named by the only argument, or standard input
+// if there is none or it is "-".
+func readSource(cmd string, args []string) (name, src string, err error) {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		return "", "", fmt.Errorf("too many arguments")
+	}
+
+	name = fs.Arg(0)
+	var b []byte
+	if name == "" || name == "-" {
+		name = "<stdin>"
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return name, string(b), nil
+}
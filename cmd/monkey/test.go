@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/yagihash/monkey/testrunner"
+)
+
+func testCommand(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	run := fs.String("run", "", "run only the tests whose names match `regexp`")
+	junit := fs.Bool("junit", false, "print the results as JUnit XML")
+	fs.Parse(args)
+
+	if fs.NArg() > 1 {
+		return errors.New("usage: monkey test [-run regexp] [-junit] [dir]")
+	}
+
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	var opts []testrunner.Option
+	if *run != "" {
+		re, err := regexp.Compile(*run)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, testrunner.WithFilter(re))
+	}
+
+	results, err := testrunner.New(os.DirFS(dir), opts...).Run()
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintln(os.Stderr, "no tests to run")
+		return nil
+	}
+
+	write := testrunner.WriteText
+	if *junit {
+		write = testrunner.WriteJUnit
+	}
+	if err := write(os.Stdout, results); err != nil {
+		return err
+	}
+
+	if !testrunner.Summarize(results).OK() {
+		return errors.New("tests failed")
+	}
+	return nil
+}
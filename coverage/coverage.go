@@ -0,0 +1,229 @@
+// Package coverage records the statements and branches of monkey files that
+// run, as an evaluator.Coverage, and reports the percentage of them covered.
+//
+// Every statement is a block, counting how many times it ran. Every if
+// expression has two blocks, counting how many times its consequence and its
+// alternative were taken; the alternative of an if without else is taken
+// when its condition is false.
+package coverage
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/token"
+)
+
+// Kind tells what a Block counts.
+type Kind string
+
+const (
+	KindStatement Kind = "stmt"
+	KindThen      Kind = "then"
+	KindElse      Kind = "else"
+)
+
+var kindOrder = map[Kind]int{KindStatement: 0, KindThen: 1, KindElse: 2}
+
+// Block is a statement, or an arm of an if expression, starting at Line and
+// Column of its file.
+type Block struct {
+	Line   int
+	Column int
+	Kind   Kind
+	Count  int64
+}
+
+type blockKey struct {
+	line, column int
+	kind         Kind
+}
+
+// File holds the blocks of a file. Profile.Files orders them by position.
+type File struct {
+	Name   string
+	Blocks []*Block
+
+	index map[blockKey]*Block
+}
+
+// Statements returns the number of statements of f that ran, and of all of
+// them.
+func (f *File) Statements() (covered, total int) {
+	return f.count(func(b *Block) bool { return b.Kind == KindStatement })
+}
+
+// Branches returns the number of arms of the if expressions of f that were
+// taken, and of all of them.
+func (f *File) Branches() (covered, total int) {
+	return f.count(func(b *Block) bool { return b.Kind != KindStatement })
+}
+
+func (f *File) count(match func(*Block) bool) (covered, total int) {
+	for _, b := range f.Blocks {
+		if !match(b) {
+			continue
+		}
+		total++
+		if b.Count > 0 {
+			covered++
+		}
+	}
+	return covered, total
+}
+
+func (f *File) block(line, column int, kind Kind) *Block {
+	key := blockKey{line, column, kind}
+	if b, ok := f.index[key]; ok {
+		return b
+	}
+
+	b := &Block{Line: line, Column: column, Kind: kind}
+	f.index[key] = b
+	f.Blocks = append(f.Blocks, b)
+
+	return b
+}
+
+func (f *File) copy() *File {
+	blocks := make([]*Block, len(f.Blocks))
+	for i, b := range f.Blocks {
+		c := *b
+		blocks[i] = &c
+	}
+	return &File{Name: f.Name, Blocks: blocks}
+}
+
+func (f *File) sortBlocks() {
+	sort.Slice(f.Blocks, func(i, j int) bool {
+		bi, bj := f.Blocks[i], f.Blocks[j]
+		if bi.Line != bj.Line {
+			return bi.Line < bj.Line
+		}
+		if bi.Column != bj.Column {
+			return bi.Column < bj.Column
+		}
+		return kindOrder[bi.Kind] < kindOrder[bj.Kind]
+	})
+}
+
+// Profile holds the blocks of the files added to it. It implements
+// evaluator.Coverage, counting the blocks of those files an Evaluator runs.
+// It is safe for concurrent use, as by the Evaluators of spawned functions.
+type Profile struct {
+	mu sync.Mutex
+
+	dir   string
+	files map[string]*File
+
+	statements map[ast.Statement]*Block
+	branches   map[*ast.IfExpression][2]*Block
+}
+
+// New returns an empty Profile. Modules are named by joining dir, the
+// directory of the file system their Evaluator loads them from, and their
+// path.
+func New(dir string) *Profile {
+	return &Profile{
+		dir:        dir,
+		files:      make(map[string]*File),
+		statements: make(map[ast.Statement]*Block),
+		branches:   make(map[*ast.IfExpression][2]*Block),
+	}
+}
+
+// Add adds the blocks of program, read from the file called name, so that
+// running it is recorded. Adding another program for the same file merges
+// the blocks at the same positions.
+func (p *Profile) Add(name string, program *ast.Program) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f := p.file(name)
+
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Program, *ast.BlockStatement:
+		case ast.Statement:
+			pos := position(n)
+			p.statements[n] = f.block(pos.Line, pos.Column, KindStatement)
+		case *ast.IfExpression:
+			pos := n.Token.Pos
+			p.branches[n] = [2]*Block{
+				f.block(pos.Line, pos.Column, KindThen),
+				f.block(pos.Line, pos.Column, KindElse),
+			}
+		}
+		return true
+	})
+}
+
+// Files returns a copy of the files of p ordered by name.
+func (p *Profile) Files() []*File {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	files := make([]*File, 0, len(p.files))
+	for _, f := range p.files {
+		f.sortBlocks()
+		files = append(files, f.copy())
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+// Module implements evaluator.Coverage.
+func (p *Profile) Module(path string, program *ast.Program) {
+	p.Add(filepath.Join(p.dir, filepath.FromSlash(path)), program)
+}
+
+// Statement implements evaluator.Coverage.
+func (p *Profile) Statement(stmt ast.Statement) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if b, ok := p.statements[stmt]; ok {
+		b.Count++
+	}
+}
+
+// Branch implements evaluator.Coverage.
+func (p *Profile) Branch(ie *ast.IfExpression, consequence bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	arms, ok := p.branches[ie]
+	if !ok {
+		return
+	}
+
+	if consequence {
+		arms[0].Count++
+	} else {
+		arms[1].Count++
+	}
+}
+
+func (p *Profile) file(name string) *File {
+	if f, ok := p.files[name]; ok {
+		return f
+	}
+
+	f := &File{Name: name, index: make(map[blockKey]*Block)}
+	p.files[name] = f
+	return f
+}
+
+func position(stmt ast.Statement) token.Position {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Pos
+	case *ast.ReturnStatement:
+		return stmt.Token.Pos
+	case *ast.ExpressionStatement:
+		return stmt.Token.Pos
+	}
+	return token.Position{}
+}
@@ -0,0 +1,186 @@
+package coverage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+const script = `let lib = import "lib.monkey";
+let sign = fn(n) {
+  if (n < 0) { return -1; }
+  if (n == 0) { 0 } else { 1 }
+};
+sign(5);
+lib.twice(2)
+`
+
+func runScript(t *testing.T) *Profile {
+	t.Helper()
+
+	p := parser.New(lexer.New(script))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %q", p.Errors())
+	}
+
+	fsys := fstest.MapFS{
+		"lib.monkey": {Data: []byte("let twice = fn(x) { x * 2 };\nlet unused = fn() { 1 };\n")},
+	}
+
+	cov := New("lib")
+	cov.Add("main.monkey", program)
+
+	e := evaluator.New(evaluator.WithModuleLoader(evaluator.NewModuleLoader(fsys)), evaluator.WithCoverage(cov))
+	if result := e.Eval(program, object.NewEnvironment()); result.Inspect() != "4" {
+		t.Fatalf("unexpected result. got=%s", result.Inspect())
+	}
+
+	return cov
+}
+
+const profile = `mode: count
+lib/lib.monkey:1.1 stmt 1
+lib/lib.monkey:1.21 stmt 1
+lib/lib.monkey:2.1 stmt 1
+lib/lib.monkey:2.21 stmt 0
+main.monkey:1.1 stmt 1
+main.monkey:2.1 stmt 1
+main.monkey:3.3 stmt 1
+main.monkey:3.3 then 0
+main.monkey:3.3 else 1
+main.monkey:3.16 stmt 0
+main.monkey:4.3 stmt 1
+main.monkey:4.3 then 0
+main.monkey:4.3 else 1
+main.monkey:4.17 stmt 0
+main.monkey:4.28 stmt 1
+main.monkey:6.1 stmt 1
+main.monkey:7.1 stmt 1
+`
+
+func TestProfile(t *testing.T) {
+	cov := runScript(t)
+
+	var out strings.Builder
+	if err := cov.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(profile, out.String()); diff != "" {
+		t.Errorf("unexpected profile\n%s", diff)
+	}
+
+	report := `lib/lib.monkey  statements  75.0% (3/4)    branches    n/a (0/0)
+main.monkey     statements  77.8% (7/9)    branches  50.0% (2/4)
+total           statements  76.9% (10/13)  branches  50.0% (2/4)
+`
+	out.Reset()
+	if err := cov.WriteReport(&out); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(report, out.String()); diff != "" {
+		t.Errorf("unexpected report\n%s", diff)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("RoundTrip", func(t *testing.T) {
+		p, err := Parse(strings.NewReader(profile))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out strings.Builder
+		p.Write(&out)
+		if diff := cmp.Diff(profile, out.String()); diff != "" {
+			t.Errorf("unexpected profile\n%s", diff)
+		}
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		input := "mode: count\na b:1.1 stmt 1\na b:1.1 stmt 2\nmode: count\na b:1.5 then 0\n"
+		p, err := Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ignoreIndex := cmp.FilterPath(func(p cmp.Path) bool { return p.String() == "index" }, cmp.Ignore())
+		want := []*File{{Name: "a b", Blocks: []*Block{
+			{Line: 1, Column: 1, Kind: KindStatement, Count: 3},
+			{Line: 1, Column: 5, Kind: KindThen},
+		}}}
+		if diff := cmp.Diff(want, p.Files(), ignoreIndex); diff != "" {
+			t.Errorf("unexpected files\n%s", diff)
+		}
+	})
+
+	errorCases := []struct {
+		input string
+		want  string
+	}{
+		{"", ""},
+		{"mode: set\n", `coverage: line 1: expected "mode: count", got "mode: set"`},
+		{"mode: count\nmain.monkey:1.1 stmt\n", `coverage: line 2: malformed block "main.monkey:1.1 stmt"`},
+		{"mode: count\nmain.monkey:1 stmt 1\n", `coverage: line 2: malformed block "main.monkey:1 stmt 1"`},
+		{"mode: count\nmain.monkey:1.1 stmt -1\n", `coverage: line 2: malformed block "main.monkey:1.1 stmt -1"`},
+		{"mode: count\nmain.monkey:1.1 loop 1\n", `coverage: line 2: unknown block kind "loop"`},
+	}
+
+	for _, c := range errorCases {
+		_, err := Parse(strings.NewReader(c.input))
+		if c.want == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %q: %s", c.input, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.want {
+			t.Errorf("unexpected error for %q. want=%q, got=%v", c.input, c.want, err)
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	cov := runScript(t)
+
+	var out strings.Builder
+	err := cov.WriteHTML(&out, func(name string) ([]byte, error) {
+		switch name {
+		case "main.monkey":
+			return []byte(script), nil
+		case "lib/lib.monkey":
+			return []byte("let twice = fn(x) { x * 2 };\nlet unused = fn() { 1 };\n"), nil
+		}
+		return nil, errors.New("no such file")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`<h2>main.monkey</h2>`,
+		`<span class="covered" title="stmt at column 1: 1"><span class="number">   1</span>  let lib = import &#34;lib.monkey&#34;;</span>`,
+		`<span class="partial" title="stmt at column 3: 1; then at column 3: 0; else at column 3: 1; stmt at column 16: 0"><span class="number">   3</span>    if (n &lt; 0) { return -1; }</span>`,
+		`<span class="" title=""><span class="number">   5</span>  };</span>`,
+		`<span class="partial" title="stmt at column 1: 1; stmt at column 21: 0"><span class="number">   2</span>  let unused = fn() { 1 };</span>`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("HTML does not contain %s", want)
+		}
+	}
+
+	err = cov.WriteHTML(&out, func(name string) ([]byte, error) {
+		return nil, errors.New("no such file")
+	})
+	if err == nil || err.Error() != "no such file" {
+		t.Errorf("unexpected error. got=%v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const header = "mode: count"
+
+// Write writes p to w as text: a header line followed by a line for each
+// block, holding its file, position, kind and count, such as
+//
+//	mode: count
+//	fib.monkey:2.3 then 10
+func (p *Profile) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, header)
+
+	for _, f := range p.Files() {
+		for _, b := range f.Blocks {
+			fmt.Fprintf(bw, "%s:%d.%d %s %d\n", f.Name, b.Line, b.Column, b.Kind, b.Count)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Parse reads a profile written by Write. The counts of blocks appearing
+// more than once, as in concatenated profiles, are added up.
+func Parse(r io.Reader) (*Profile, error) {
+	p := New("")
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		if n == 1 {
+			if line != header {
+				return nil, fmt.Errorf("coverage: line 1: expected %q, got %q", header, line)
+			}
+			continue
+		}
+		if line == "" || line == header {
+			continue
+		}
+
+		name, b, err := parseBlock(line)
+		if err != nil {
+			return nil, fmt.Errorf("coverage: line %d: %s", n, err)
+		}
+
+		p.file(name).block(b.Line, b.Column, b.Kind).Count += b.Count
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// parseBlock parses a line such as "fib.monkey:2.3 then 10". The file name
+// may contain spaces and colons, so the line is split from the right.
+func parseBlock(line string) (string, Block, error) {
+	malformed := fmt.Errorf("malformed block %q", line)
+
+	i := strings.LastIndex(line, " ")
+	if i < 0 {
+		return "", Block{}, malformed
+	}
+	j := strings.LastIndex(line[:i], " ")
+	if j < 0 {
+		return "", Block{}, malformed
+	}
+	k := strings.LastIndex(line[:j], ":")
+	if k < 0 {
+		return "", Block{}, malformed
+	}
+
+	count, err := strconv.ParseInt(line[i+1:], 10, 64)
+	if err != nil || count < 0 {
+		return "", Block{}, malformed
+	}
+
+	kind := Kind(line[j+1 : i])
+	if _, ok := kindOrder[kind]; !ok {
+		return "", Block{}, fmt.Errorf("unknown block kind %q", kind)
+	}
+
+	pos := strings.SplitN(line[k+1:j], ".", 2)
+	if len(pos) != 2 {
+		return "", Block{}, malformed
+	}
+	lineNo, err1 := strconv.Atoi(pos[0])
+	column, err2 := strconv.Atoi(pos[1])
+	if err1 != nil || err2 != nil {
+		return "", Block{}, malformed
+	}
+
+	return line[:k], Block{Line: lineNo, Column: column, Kind: kind, Count: count}, nil
+}
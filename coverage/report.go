@@ -0,0 +1,132 @@
+package coverage
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteReport writes the percentages of statements and branches covered in
+// each file of p, and in all of them, to w.
+func (p *Profile) WriteReport(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	var stmts, stmtsTotal, branches, branchesTotal int
+	for _, f := range p.Files() {
+		s, st := f.Statements()
+		b, bt := f.Branches()
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Name, ratio("statements", s, st), ratio("branches", b, bt))
+
+		stmts, stmtsTotal = stmts+s, stmtsTotal+st
+		branches, branchesTotal = branches+b, branchesTotal+bt
+	}
+	fmt.Fprintf(tw, "total\t%s\t%s\n", ratio("statements", stmts, stmtsTotal), ratio("branches", branches, branchesTotal))
+
+	return tw.Flush()
+}
+
+func ratio(what string, covered, total int) string {
+	if total == 0 {
+		return fmt.Sprintf("%s    n/a (0/0)", what)
+	}
+	return fmt.Sprintf("%s %5.1f%% (%d/%d)", what, 100*float64(covered)/float64(total), covered, total)
+}
+
+type htmlFile struct {
+	Name    string
+	Summary string
+	Lines   []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Text   string
+	Class  string
+	Title  string
+}
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>monkey coverage</title>
+<style>
+body { font-family: sans-serif; }
+pre { font-family: monospace; }
+.covered { background: #c8f0c8; }
+.partial { background: #f8eaa0; }
+.uncovered { background: #f4c0c0; }
+.number { color: #888; user-select: none; }
+</style>
+</head>
+<body>
+{{range .}}<h2>{{.Name}}</h2>
+<p>{{.Summary}}</p>
+<pre>{{range .Lines}}<span class="{{.Class}}" title="{{.Title}}"><span class="number">{{printf "%4d" .Number}}</span>  {{.Text}}</span>
+{{end}}</pre>
+{{end}}</body>
+</html>
+`))
+
+// WriteHTML writes the source of the files of p to w as HTML, marking the
+// lines whose blocks all ran as covered, those where only some did as
+// partial, and those where none did as uncovered. readFile reads the source
+// of a file by name.
+func (p *Profile) WriteHTML(w io.Writer, readFile func(name string) ([]byte, error)) error {
+	var files []htmlFile
+	for _, f := range p.Files() {
+		src, err := readFile(f.Name)
+		if err != nil {
+			return err
+		}
+
+		s, st := f.Statements()
+		b, bt := f.Branches()
+		files = append(files, htmlFile{
+			Name:    f.Name,
+			Summary: ratio("statements", s, st) + ", " + ratio("branches", b, bt),
+			Lines:   annotate(f, string(src)),
+		})
+	}
+
+	return htmlTemplate.Execute(w, files)
+}
+
+func annotate(f *File, src string) []htmlLine {
+	blocks := make(map[int][]*Block)
+	for _, b := range f.Blocks {
+		blocks[b.Line] = append(blocks[b.Line], b)
+	}
+
+	var lines []htmlLine
+	for i, text := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+		line := htmlLine{Number: i + 1, Text: text}
+
+		var run, notRun int
+		var title []string
+		for _, b := range blocks[i+1] {
+			if b.Count > 0 {
+				run++
+			} else {
+				notRun++
+			}
+			title = append(title, fmt.Sprintf("%s at column %d: %d", b.Kind, b.Column, b.Count))
+		}
+
+		switch {
+		case run > 0 && notRun == 0:
+			line.Class = "covered"
+		case run > 0:
+			line.Class = "partial"
+		case notRun > 0:
+			line.Class = "uncovered"
+		}
+		line.Title = strings.Join(title, "; ")
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
@@ -0,0 +1,559 @@
+// Package dap implements the Debug Adapter Protocol on top of the debugger
+// package, so that editors can debug monkey programs.
+//
+// A Server debugs a single program in a single thread. It supports the
+// initialize, launch, setBreakpoints, configurationDone, threads, stackTrace,
+// scopes, variables, continue, next, stepIn, stepOut, evaluate and disconnect
+// requests.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/yagihash/monkey/debugger"
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+// threadID identifies the only thread of a monkey program.
+const threadID = 1
+
+type handler func(s *Server, args json.RawMessage) (interface{}, error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"initialize":        (*Server).initialize,
+		"launch":            (*Server).launch,
+		"setBreakpoints":    (*Server).setBreakpoints,
+		"configurationDone": (*Server).configurationDone,
+		"threads":           (*Server).threads,
+		"stackTrace":        (*Server).stackTrace,
+		"scopes":            (*Server).scopes,
+		"variables":         (*Server).variables,
+		"continue":          resume((*debugger.Debugger).Continue),
+		"next":              resume((*debugger.Debugger).StepOver),
+		"stepIn":            resume((*debugger.Debugger).StepIn),
+		"stepOut":           resume((*debugger.Debugger).StepOut),
+		"evaluate":          (*Server).evaluate,
+		"disconnect":        (*Server).disconnect,
+	}
+}
+
+type Server struct {
+	r *bufio.Reader
+
+	// mu guards the writes to the client, which the program goroutine makes
+	// when the program prints something.
+	mu  sync.Mutex
+	w   io.Writer
+	seq int
+
+	d           *debugger.Debugger
+	path        string
+	stopOnEntry bool
+	launched    bool
+	configured  bool
+	started     bool
+	stops       chan debugger.Event
+
+	// breakpoints holds the lines requested per source, by absolute path,
+	// until the program is launched.
+	breakpoints map[string][]int
+
+	// refs holds the children of each variablesReference handed out since
+	// the program last stopped; reference n is refs[n-1].
+	refs []func() []variable
+
+	// deferred holds the messages to send after the current response.
+	deferred []func()
+	done     bool
+}
+
+// NewServer returns a Server reading requests from r and writing responses
+// and events to w.
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		r:           bufio.NewReader(r),
+		w:           w,
+		stops:       make(chan debugger.Event, 1),
+		breakpoints: make(map[string][]int),
+	}
+}
+
+// Serve handles requests until the client disconnects or closes the input.
+func (s *Server) Serve() error {
+	requests := make(chan request)
+	errc := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	go func() {
+		for {
+			var req request
+			if err := readMessage(s.r, &req); err != nil {
+				errc <- err
+				return
+			}
+
+			select {
+			case requests <- req:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	for !s.done {
+		select {
+		case req := <-requests:
+			if err := s.handle(req); err != nil {
+				return err
+			}
+		case ev := <-s.stops:
+			if err := s.stopped(ev); err != nil {
+				return err
+			}
+		case err := <-errc:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) handle(req request) error {
+	resp := &response{
+		Type:       "response",
+		RequestSeq: req.Seq,
+		Command:    req.Command,
+	}
+
+	h, ok := handlers[req.Command]
+	if !ok {
+		resp.Message = fmt.Sprintf("unsupported command: %s", req.Command)
+	} else if body, err := h(s, req.Arguments); err != nil {
+		resp.Message = err.Error()
+	} else {
+		resp.Success = true
+		resp.Body = body
+	}
+
+	if err := s.send(resp); err != nil {
+		return err
+	}
+
+	deferred := s.deferred
+	s.deferred = nil
+	for _, f := range deferred {
+		f()
+	}
+
+	return nil
+}
+
+func (s *Server) send(msg interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+
+	return writeMessage(s.w, msg)
+}
+
+func (s *Server) event(name string, body interface{}) error {
+	return s.send(&event{Type: "event", Event: name, Body: body})
+}
+
+func unmarshal(args json.RawMessage, v interface{}) error {
+	if len(args) == 0 {
+		return nil
+	}
+	return json.Unmarshal(args, v)
+}
+
+func (s *Server) initialize(args json.RawMessage) (interface{}, error) {
+	s.deferred = append(s.deferred, func() { s.event("initialized", nil) })
+
+	return capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsEvaluateForHovers:        true,
+	}, nil
+}
+
+func (s *Server) launch(args json.RawMessage) (interface{}, error) {
+	if s.launched {
+		return nil, errors.New("program already launched")
+	}
+
+	var la launchArguments
+	if err := unmarshal(args, &la); err != nil {
+		return nil, err
+	}
+
+	if la.Program == "" {
+		return nil, errors.New("no program to launch")
+	}
+
+	path, err := filepath.Abs(la.Program)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		return nil, fmt.Errorf("%s:%s", la.Program, p.Diagnostics()[0])
+	}
+
+	s.d = debugger.New(program,
+		evaluator.WithModuleLoader(evaluator.NewModuleLoader(os.DirFS(filepath.Dir(path)))),
+		evaluator.WithStdout(outputWriter{s: s, category: "stdout"}),
+		evaluator.WithStderr(outputWriter{s: s, category: "stderr"}),
+	)
+	s.d.SetBreakpoints(s.breakpoints[path])
+
+	s.path = path
+	s.stopOnEntry = la.StopOnEntry
+	s.launched = true
+	s.deferred = append(s.deferred, s.start)
+
+	return nil, nil
+}
+
+func (s *Server) configurationDone(args json.RawMessage) (interface{}, error) {
+	s.configured = true
+	s.deferred = append(s.deferred, s.start)
+	return nil, nil
+}
+
+// start runs the program once it is launched and the client is done
+// configuring it.
+func (s *Server) start() {
+	if !s.launched || !s.configured || s.started {
+		return
+	}
+
+	s.started = true
+	s.d.Start(object.NewEnvironment(), s.stopOnEntry)
+	go s.wait()
+}
+
+func (s *Server) wait() {
+	s.stops <- s.d.Wait()
+}
+
+func (s *Server) stopped(ev debugger.Event) error {
+	s.refs = nil
+
+	if ev.Reason != debugger.ReasonExited {
+		return s.event("stopped", map[string]interface{}{
+			"reason":            string(ev.Reason),
+			"threadId":          threadID,
+			"allThreadsStopped": true,
+		})
+	}
+
+	exitCode := 0
+	if err, ok := ev.Result.(*object.Error); ok {
+		exitCode = 1
+		s.event("output", map[string]string{"category": "stderr", "output": err.Inspect() + "\n"})
+	}
+
+	if err := s.event("exited", map[string]int{"exitCode": exitCode}); err != nil {
+		return err
+	}
+	return s.event("terminated", nil)
+}
+
+func (s *Server) setBreakpoints(args json.RawMessage) (interface{}, error) {
+	var ba setBreakpointsArguments
+	if err := unmarshal(args, &ba); err != nil {
+		return nil, err
+	}
+
+	path, err := filepath.Abs(ba.Source.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]int, 0, len(ba.Breakpoints))
+	for _, b := range ba.Breakpoints {
+		lines = append(lines, b.Line)
+	}
+	s.breakpoints[path] = lines
+
+	var verified []int
+	message := ""
+	switch {
+	case !s.launched:
+		message = "the program has not been launched yet"
+	case path != s.path:
+		message = "not the launched program"
+	default:
+		verified = s.d.SetBreakpoints(lines)
+		message = "no statement on this line"
+	}
+
+	breakpoints := make([]breakpoint, 0, len(lines))
+	for _, l := range lines {
+		b := breakpoint{Line: l, Verified: containsLine(verified, l)}
+		if !b.Verified {
+			b.Message = message
+		}
+		breakpoints = append(breakpoints, b)
+	}
+
+	return map[string]interface{}{"breakpoints": breakpoints}, nil
+}
+
+func containsLine(lines []int, line int) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) threads(args json.RawMessage) (interface{}, error) {
+	return map[string]interface{}{
+		"threads": []thread{{ID: threadID, Name: "main"}},
+	}, nil
+}
+
+func (s *Server) stack() ([]debugger.Frame, error) {
+	if !s.started {
+		return nil, errors.New("the program is not running")
+	}
+	return s.d.Stack()
+}
+
+func (s *Server) stackTrace(args json.RawMessage) (interface{}, error) {
+	frames, err := s.stack()
+	if err != nil {
+		return nil, err
+	}
+
+	src := source{Name: filepath.Base(s.path), Path: s.path}
+
+	stackFrames := make([]stackFrame, 0, len(frames))
+	for i, f := range frames {
+		stackFrames = append(stackFrames, stackFrame{
+			ID:     i + 1,
+			Name:   f.Name,
+			Source: src,
+			Line:   f.Line,
+			Column: 1,
+		})
+	}
+
+	return map[string]interface{}{
+		"stackFrames": stackFrames,
+		"totalFrames": len(stackFrames),
+	}, nil
+}
+
+// frame returns the frame with the given id, or the innermost frame for id 0.
+func (s *Server) frame(id int) (debugger.Frame, int, error) {
+	frames, err := s.stack()
+	if err != nil {
+		return debugger.Frame{}, 0, err
+	}
+
+	if id == 0 {
+		id = 1
+	}
+	if id < 1 || id > len(frames) {
+		return debugger.Frame{}, 0, fmt.Errorf("no frame with id %d", id)
+	}
+
+	return frames[id-1], id - 1, nil
+}
+
+// scopes returns a scope for each environment in the chain of the frame: its
+// locals, the environments of enclosing functions and the globals.
+func (s *Server) scopes(args json.RawMessage) (interface{}, error) {
+	var fa frameArguments
+	if err := unmarshal(args, &fa); err != nil {
+		return nil, err
+	}
+
+	f, _, err := s.frame(fa.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []scope{}
+	for env := f.Env; env != nil; env = env.Outer() {
+		name := "Closure"
+		switch {
+		case env.Outer() == nil:
+			name = "Globals"
+		case env == f.Env:
+			name = "Locals"
+		}
+
+		env := env
+		scopes = append(scopes, scope{
+			Name:               name,
+			VariablesReference: s.ref(func() []variable { return s.envVariables(env) }),
+		})
+	}
+
+	return map[string]interface{}{"scopes": scopes}, nil
+}
+
+func (s *Server) variables(args json.RawMessage) (interface{}, error) {
+	var va variablesArguments
+	if err := unmarshal(args, &va); err != nil {
+		return nil, err
+	}
+
+	if va.VariablesReference < 1 || va.VariablesReference > len(s.refs) {
+		return nil, fmt.Errorf("unknown variablesReference %d", va.VariablesReference)
+	}
+
+	return map[string]interface{}{
+		"variables": s.refs[va.VariablesReference-1](),
+	}, nil
+}
+
+func (s *Server) ref(children func() []variable) int {
+	s.refs = append(s.refs, children)
+	return len(s.refs)
+}
+
+func (s *Server) envVariables(env *object.Environment) []variable {
+	vars := []variable{}
+	for _, name := range env.Names() {
+		val, _ := env.Get(name)
+		vars = append(vars, s.variable(name, val))
+	}
+	return vars
+}
+
+// variable describes val, giving arrays and hashes a reference to their
+// elements.
+func (s *Server) variable(name string, val object.Object) variable {
+	v := variable{Name: name, Value: val.Inspect(), Type: string(val.Type())}
+
+	switch val := val.(type) {
+	case *object.Array:
+		v.VariablesReference = s.ref(func() []variable {
+			vars := []variable{}
+			for i, el := range val.Elements {
+				vars = append(vars, s.variable(fmt.Sprintf("[%d]", i), el))
+			}
+			return vars
+		})
+	case *object.Hash:
+		v.VariablesReference = s.ref(func() []variable {
+			vars := []variable{}
+			for _, pair := range val.Pairs {
+				vars = append(vars, s.variable(pair.Key.Inspect(), pair.Value))
+			}
+			sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
+			return vars
+		})
+	}
+
+	return v
+}
+
+func resume(step func(*debugger.Debugger) error) handler {
+	return func(s *Server, args json.RawMessage) (interface{}, error) {
+		if !s.started {
+			return nil, errors.New("the program is not running")
+		}
+
+		if err := step(s.d); err != nil {
+			return nil, err
+		}
+
+		s.refs = nil
+		go s.wait()
+
+		return map[string]bool{"allThreadsContinued": true}, nil
+	}
+}
+
+func (s *Server) evaluate(args json.RawMessage) (interface{}, error) {
+	var ea evaluateArguments
+	if err := unmarshal(args, &ea); err != nil {
+		return nil, err
+	}
+
+	_, index, err := s.frame(ea.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := s.d.Evaluate(ea.Expression, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if val == nil {
+		return map[string]interface{}{"result": "", "variablesReference": 0}, nil
+	}
+
+	if err, ok := val.(*object.Error); ok {
+		return nil, errors.New(err.Message)
+	}
+
+	v := s.variable("", val)
+	return map[string]interface{}{
+		"result":             v.Value,
+		"type":               v.Type,
+		"variablesReference": v.VariablesReference,
+	}, nil
+}
+
+// disconnect terminates the program, if it was started, and ends Serve.
+func (s *Server) disconnect(args json.RawMessage) (interface{}, error) {
+	if s.started {
+		s.d.Terminate()
+	}
+	s.done = true
+	return nil, nil
+}
+
+// outputWriter sends what the program prints to the client.
+type outputWriter struct {
+	s        *Server
+	category string
+}
+
+func (w outputWriter) Write(p []byte) (int, error) {
+	err := w.s.event("output", map[string]string{"category": w.category, "output": string(p)})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
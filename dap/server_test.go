@@ -0,0 +1,402 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const script = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let x = add(1, 2);
+let y = add(x, 10);
+puts(y * 2);
+`
+
+// message is any message sent by the server.
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Event      string          `json:"event"`
+	Command    string          `json:"command"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client drives a Server the way an editor would.
+type client struct {
+	t      *testing.T
+	r      *bufio.Reader
+	w      io.Writer
+	seq    int
+	events []message
+	output []message
+}
+
+func newClient(t *testing.T) (*client, chan error) {
+	t.Helper()
+
+	reqR, reqW := io.Pipe()
+	respR, respW := io.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- NewServer(reqR, respW).Serve()
+		respW.Close()
+	}()
+
+	return &client{t: t, r: bufio.NewReader(respR), w: reqW}, done
+}
+
+func (c *client) read() message {
+	c.t.Helper()
+
+	var msg message
+	if err := readMessage(c.r, &msg); err != nil {
+		c.t.Fatalf("could not read message: %s", err)
+	}
+	return msg
+}
+
+// request sends a request and returns its response, keeping the events that
+// arrive before it.
+func (c *client) request(command string, args interface{}) message {
+	c.t.Helper()
+
+	c.seq++
+	req := map[string]interface{}{"seq": c.seq, "type": "request", "command": command}
+	if args != nil {
+		req["arguments"] = args
+	}
+	if err := writeMessage(c.w, req); err != nil {
+		c.t.Fatalf("could not send %s: %s", command, err)
+	}
+
+	for {
+		msg := c.read()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+
+		if msg.RequestSeq != c.seq || msg.Command != command {
+			c.t.Fatalf("unexpected response to %s: %+v", command, msg)
+		}
+		return msg
+	}
+}
+
+// succeed sends a request that must succeed and decodes its body into v.
+func (c *client) succeed(command string, args interface{}, v interface{}) {
+	c.t.Helper()
+
+	resp := c.request(command, args)
+	if !resp.Success {
+		c.t.Fatalf("%s failed: %s", command, resp.Message)
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(resp.Body, v); err != nil {
+			c.t.Fatalf("could not decode the body of %s: %s", command, err)
+		}
+	}
+}
+
+// event waits for the named event and decodes its body into v. Output events
+// on the way are kept in c.output.
+func (c *client) event(name string, v interface{}) {
+	c.t.Helper()
+
+	for {
+		var msg message
+		if len(c.events) != 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.read()
+		}
+
+		if msg.Type != "event" {
+			c.t.Fatalf("unexpected message while waiting for %s: %+v", name, msg)
+		}
+		if msg.Event != name {
+			if msg.Event != "output" {
+				c.t.Fatalf("unexpected %s event while waiting for %s", msg.Event, name)
+			}
+			c.output = append(c.output, msg)
+			continue
+		}
+
+		if v != nil {
+			if err := json.Unmarshal(msg.Body, v); err != nil {
+				c.t.Fatalf("could not decode the body of %s: %s", name, err)
+			}
+		}
+		return
+	}
+}
+
+type stopped struct {
+	Reason   string `json:"reason"`
+	ThreadID int    `json:"threadId"`
+}
+
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "add.monkey")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestServer(t *testing.T) {
+	path := writeScript(t, script)
+	c, done := newClient(t)
+
+	var caps capabilities
+	c.succeed("initialize", map[string]string{"adapterID": "monkey"}, &caps)
+	if !caps.SupportsConfigurationDoneRequest {
+		t.Errorf("configurationDone is not supported")
+	}
+	c.event("initialized", nil)
+
+	var bps struct{ Breakpoints []breakpoint }
+	c.succeed("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": path},
+		"breakpoints": []map[string]int{{"line": 2}},
+	}, &bps)
+	want := []breakpoint{{Line: 2, Message: "the program has not been launched yet"}}
+	if diff := cmp.Diff(want, bps.Breakpoints); diff != "" {
+		t.Errorf("unexpected breakpoints\n%s", diff)
+	}
+
+	c.succeed("launch", map[string]interface{}{"program": path}, nil)
+
+	bps.Breakpoints = nil
+	c.succeed("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": path},
+		"breakpoints": []map[string]int{{"line": 2}, {"line": 4}},
+	}, &bps)
+	want = []breakpoint{{Line: 2, Verified: true}, {Line: 4, Message: "no statement on this line"}}
+	if diff := cmp.Diff(want, bps.Breakpoints); diff != "" {
+		t.Errorf("unexpected breakpoints\n%s", diff)
+	}
+
+	c.succeed("configurationDone", nil, nil)
+
+	var stop stopped
+	c.event("stopped", &stop)
+	if diff := cmp.Diff(stopped{Reason: "breakpoint", ThreadID: threadID}, stop); diff != "" {
+		t.Errorf("unexpected stop\n%s", diff)
+	}
+
+	var threads struct{ Threads []thread }
+	c.succeed("threads", nil, &threads)
+	if diff := cmp.Diff([]thread{{ID: 1, Name: "main"}}, threads.Threads); diff != "" {
+		t.Errorf("unexpected threads\n%s", diff)
+	}
+
+	var trace struct {
+		StackFrames []stackFrame
+		TotalFrames int
+	}
+	c.succeed("stackTrace", map[string]int{"threadId": threadID}, &trace)
+	src := source{Name: "add.monkey", Path: path}
+	wantFrames := []stackFrame{
+		{ID: 1, Name: "add", Source: src, Line: 2, Column: 1},
+		{ID: 2, Name: "main", Source: src, Line: 5, Column: 1},
+	}
+	if diff := cmp.Diff(wantFrames, trace.StackFrames); diff != "" {
+		t.Errorf("unexpected stack trace\n%s", diff)
+	}
+
+	var scopes struct{ Scopes []scope }
+	c.succeed("scopes", map[string]int{"frameId": 1}, &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" || scopes.Scopes[1].Name != "Globals" {
+		t.Fatalf("unexpected scopes: %+v", scopes.Scopes)
+	}
+
+	var vars struct{ Variables []variable }
+	c.succeed("variables", map[string]int{"variablesReference": scopes.Scopes[0].VariablesReference}, &vars)
+	wantVars := []variable{
+		{Name: "a", Value: "1", Type: "INTEGER"},
+		{Name: "b", Value: "2", Type: "INTEGER"},
+	}
+	if diff := cmp.Diff(wantVars, vars.Variables); diff != "" {
+		t.Errorf("unexpected locals\n%s", diff)
+	}
+
+	c.succeed("variables", map[string]int{"variablesReference": scopes.Scopes[1].VariablesReference}, &vars)
+	if len(vars.Variables) != 1 || vars.Variables[0].Name != "add" || vars.Variables[0].Type != "FUNCTION" {
+		t.Errorf("unexpected globals: %+v", vars.Variables)
+	}
+
+	var result struct {
+		Result             string
+		Type               string
+		VariablesReference int
+	}
+	c.succeed("evaluate", map[string]interface{}{"expression": "a + b", "frameId": 1}, &result)
+	if result.Result != "3" || result.Type != "INTEGER" {
+		t.Errorf("unexpected result of a + b: %+v", result)
+	}
+
+	c.succeed("evaluate", map[string]interface{}{"expression": "[a, {\"b\": b}]"}, &result)
+	c.succeed("variables", map[string]int{"variablesReference": result.VariablesReference}, &vars)
+	if len(vars.Variables) != 2 || vars.Variables[1].Name != "[1]" || vars.Variables[1].VariablesReference == 0 {
+		t.Fatalf("unexpected elements: %+v", vars.Variables)
+	}
+	c.succeed("variables", map[string]int{"variablesReference": vars.Variables[1].VariablesReference}, &vars)
+	if diff := cmp.Diff([]variable{{Name: "b", Value: "2", Type: "INTEGER"}}, vars.Variables); diff != "" {
+		t.Errorf("unexpected hash pairs\n%s", diff)
+	}
+
+	resp := c.request("evaluate", map[string]interface{}{"expression": "x", "frameId": 2})
+	if resp.Success || resp.Message != "identifier not found: x" {
+		t.Errorf("unexpected response to evaluating x: %+v", resp)
+	}
+
+	c.succeed("next", map[string]int{"threadId": threadID}, nil)
+	c.event("stopped", &stop)
+	c.succeed("stackTrace", map[string]int{"threadId": threadID}, &trace)
+	if stop.Reason != "step" || trace.StackFrames[0].Line != 3 {
+		t.Errorf("unexpected stop after next: %+v at %+v", stop, trace.StackFrames[0])
+	}
+
+	c.succeed("continue", map[string]int{"threadId": threadID}, nil)
+	c.event("stopped", &stop)
+	c.succeed("evaluate", map[string]interface{}{"expression": "a"}, &result)
+	if stop.Reason != "breakpoint" || result.Result != "3" {
+		t.Errorf("unexpected stop in the second call: %+v with a=%s", stop, result.Result)
+	}
+
+	c.succeed("setBreakpoints", map[string]interface{}{
+		"source":      map[string]string{"path": path},
+		"breakpoints": []map[string]int{},
+	}, nil)
+	c.succeed("stepIn", map[string]int{"threadId": threadID}, nil)
+	c.event("stopped", &stop)
+	c.succeed("continue", map[string]int{"threadId": threadID}, nil)
+
+	var exited struct{ ExitCode int }
+	c.event("exited", &exited)
+	if exited.ExitCode != 0 {
+		t.Errorf("unexpected exit code %d", exited.ExitCode)
+	}
+	c.event("terminated", nil)
+
+	var output struct{ Category, Output string }
+	if len(c.output) != 1 || json.Unmarshal(c.output[0].Body, &output) != nil || output.Output != "26\n" || output.Category != "stdout" {
+		t.Errorf("unexpected output events: %+v", c.output)
+	}
+
+	if resp := c.request("stackTrace", map[string]int{"threadId": threadID}); resp.Success || resp.Message != "program has exited" {
+		t.Errorf("unexpected response to stackTrace after exiting: %+v", resp)
+	}
+
+	c.succeed("disconnect", nil, nil)
+	if err := <-done; err != nil {
+		t.Errorf("Serve failed: %s", err)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	c, done := newClient(t)
+
+	c.succeed("initialize", nil, nil)
+
+	cases := []struct {
+		command string
+		args    interface{}
+		want    string
+	}{
+		{"attach", nil, "unsupported command: attach"},
+		{"launch", map[string]string{}, "no program to launch"},
+		{"launch", map[string]string{"program": writeScript(t, "let = 1;")}, "add.monkey:1:5: expected next token to be IDENT, got = instead"},
+		{"stackTrace", map[string]int{"threadId": threadID}, "the program is not running"},
+		{"continue", map[string]int{"threadId": threadID}, "the program is not running"},
+	}
+
+	for _, cs := range cases {
+		resp := c.request(cs.command, cs.args)
+		if resp.Success || filepath.Base(resp.Message) != cs.want {
+			t.Errorf("unexpected response to %s: %+v", cs.command, resp)
+		}
+	}
+
+	c.w.(io.Closer).Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve failed: %s", err)
+	}
+}
+
+func TestServerStopOnEntry(t *testing.T) {
+	path := writeScript(t, "let x = 1;\nlet y = fn() { error };\ny()\n")
+	c, done := newClient(t)
+
+	c.succeed("initialize", nil, nil)
+	c.succeed("configurationDone", nil, nil)
+	c.succeed("launch", map[string]interface{}{"program": path, "stopOnEntry": true}, nil)
+
+	var stop stopped
+	c.event("initialized", nil)
+	c.event("stopped", &stop)
+	if stop.Reason != "entry" {
+		t.Errorf("unexpected stop: %+v", stop)
+	}
+
+	c.succeed("continue", nil, nil)
+
+	var exited struct{ ExitCode int }
+	c.event("exited", &exited)
+	if exited.ExitCode != 1 {
+		t.Errorf("unexpected exit code %d", exited.ExitCode)
+	}
+
+	var output struct{ Category, Output string }
+	if len(c.output) != 1 {
+		t.Fatalf("unexpected output events: %+v", c.output)
+	}
+	json.Unmarshal(c.output[0].Body, &output)
+	if output.Category != "stderr" || output.Output != "ERROR: identifier not found: error\n" {
+		t.Errorf("unexpected output: %+v", output)
+	}
+
+	c.succeed("disconnect", nil, nil)
+	<-done
+}
+
+func TestServerDisconnect(t *testing.T) {
+	path := writeScript(t, "let x = 1;\nputs(x);\n")
+	c, done := newClient(t)
+
+	c.succeed("initialize", nil, nil)
+	c.succeed("configurationDone", nil, nil)
+	c.succeed("launch", map[string]interface{}{"program": path, "stopOnEntry": true}, nil)
+	c.event("initialized", nil)
+	c.event("stopped", nil)
+
+	// The program is terminated rather than left blocked at its entry, and
+	// the rest of it does not run.
+	c.succeed("disconnect", nil, nil)
+	if err := <-done; err != nil {
+		t.Errorf("Serve failed: %s", err)
+	}
+
+	var msg message
+	for readMessage(c.r, &msg) == nil {
+		if msg.Event == "output" {
+			t.Errorf("unexpected output after disconnecting: %s", msg.Body)
+		}
+	}
+}
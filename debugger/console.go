@@ -0,0 +1,332 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yagihash/monkey/object"
+)
+
+const consolePrompt = "(debug) "
+
+// Console is a line-oriented user interface to a Debugger, reading commands
+// such as "break 3" or "step" from its input.
+type Console struct {
+	d     *Debugger
+	name  string
+	lines []string
+
+	in  *bufio.Scanner
+	out io.Writer
+
+	breakpoints map[int]bool
+	frame       int
+}
+
+// NewConsole returns a Console for d debugging src, a program read from the
+// file called name.
+func NewConsole(d *Debugger, name, src string, in io.Reader, out io.Writer) *Console {
+	return &Console{
+		d:           d,
+		name:        name,
+		lines:       strings.Split(strings.TrimSuffix(src, "\n"), "\n"),
+		in:          bufio.NewScanner(in),
+		out:         out,
+		breakpoints: make(map[int]bool),
+	}
+}
+
+type consoleCommand struct {
+	name    string
+	alias   string
+	args    string
+	help    string
+	run     func(c *Console, arg string) error
+	resumes bool
+}
+
+var consoleCommands []consoleCommand
+
+func init() {
+	consoleCommands = []consoleCommand{
+		{name: "break", alias: "b", args: "LINE", help: "stop at LINE", run: (*Console).setBreakpoint},
+		{name: "clear", args: "LINE", help: "remove the breakpoint at LINE", run: (*Console).clearBreakpoint},
+		{name: "continue", alias: "c", help: "run until a breakpoint", run: resume((*Debugger).Continue), resumes: true},
+		{name: "step", alias: "s", help: "run to the next statement, entering calls", run: resume((*Debugger).StepIn), resumes: true},
+		{name: "next", alias: "n", help: "run to the next statement in this function", run: resume((*Debugger).StepOver), resumes: true},
+		{name: "out", alias: "o", help: "run until this function returns", run: resume((*Debugger).StepOut), resumes: true},
+		{name: "backtrace", alias: "bt", help: "print the call stack", run: (*Console).backtrace},
+		{name: "frame", alias: "f", args: "N", help: "select frame N of the backtrace", run: (*Console).selectFrame},
+		{name: "print", alias: "p", args: "[NAME]", help: "print a variable, or all of the frame", run: (*Console).print},
+		{name: "eval", alias: "e", args: "EXPR", help: "evaluate EXPR in the frame", run: (*Console).eval},
+		{name: "list", alias: "l", help: "print the source around the current line", run: (*Console).list},
+		{name: "help", alias: "h", help: "print this help", run: (*Console).help},
+	}
+}
+
+func lookupConsoleCommand(name string) (consoleCommand, bool) {
+	for _, cmd := range consoleCommands {
+		if cmd.name == name || cmd.alias != "" && cmd.alias == name {
+			return cmd, true
+		}
+	}
+	return consoleCommand{}, false
+}
+
+// Run debugs the program in env, stopped before its first statement, until it
+// exits, the input ends or the user quits.
+func (c *Console) Run(env *object.Environment) error {
+	c.d.Start(env, true)
+
+	for {
+		ev := c.d.Wait()
+		c.frame = 0
+
+		if ev.Reason == ReasonExited {
+			c.exited(ev.Result)
+			return nil
+		}
+
+		fmt.Fprintf(c.out, "stopped at %s:%d (%s)\n", c.name, ev.Line, ev.Reason)
+		c.printLine(ev.Line, "")
+
+		quit, err := c.prompt()
+		if err != nil || quit {
+			return err
+		}
+	}
+}
+
+// prompt runs commands until one of them resumes the program.
+func (c *Console) prompt() (quit bool, err error) {
+	for {
+		io.WriteString(c.out, consolePrompt)
+		if !c.in.Scan() {
+			return true, c.in.Err()
+		}
+
+		fields := strings.SplitN(strings.TrimSpace(c.in.Text()), " ", 2)
+		if fields[0] == "" {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "q" {
+			return true, nil
+		}
+
+		cmd, ok := lookupConsoleCommand(fields[0])
+		if !ok {
+			fmt.Fprintf(c.out, "unknown command: %s (try help)\n", fields[0])
+			continue
+		}
+
+		var arg string
+		if len(fields) > 1 {
+			arg = strings.TrimSpace(fields[1])
+		}
+
+		if err := cmd.run(c, arg); err != nil {
+			fmt.Fprintln(c.out, err)
+			continue
+		}
+
+		if cmd.resumes {
+			return false, nil
+		}
+	}
+}
+
+func (c *Console) exited(result object.Object) {
+	if result == nil {
+		io.WriteString(c.out, "program exited\n")
+		return
+	}
+	fmt.Fprintf(c.out, "program exited: %s\n", result.Inspect())
+}
+
+func resume(step func(*Debugger) error) func(*Console, string) error {
+	return func(c *Console, arg string) error {
+		return step(c.d)
+	}
+}
+
+func lineArg(arg string) (int, error) {
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 {
+		return 0, fmt.Errorf("invalid line: %q", arg)
+	}
+	return line, nil
+}
+
+func (c *Console) setBreakpoint(arg string) error {
+	line, err := lineArg(arg)
+	if err != nil {
+		return err
+	}
+
+	if set := c.d.SetBreakpoints(c.breakpointLines(line)); !contains(set, line) {
+		return fmt.Errorf("no statement on line %d", line)
+	}
+
+	c.breakpoints[line] = true
+	fmt.Fprintf(c.out, "breakpoint at %s:%d\n", c.name, line)
+	return nil
+}
+
+func (c *Console) clearBreakpoint(arg string) error {
+	line, err := lineArg(arg)
+	if err != nil {
+		return err
+	}
+
+	if !c.breakpoints[line] {
+		return fmt.Errorf("no breakpoint on line %d", line)
+	}
+
+	delete(c.breakpoints, line)
+	c.d.SetBreakpoints(c.breakpointLines())
+	return nil
+}
+
+func (c *Console) breakpointLines(extra ...int) []int {
+	lines := extra
+	for line := range c.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+func contains(lines []int, line int) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Console) backtrace(arg string) error {
+	frames, err := c.d.Stack()
+	if err != nil {
+		return err
+	}
+
+	for i, f := range frames {
+		marker := " "
+		if i == c.frame {
+			marker = "*"
+		}
+		fmt.Fprintf(c.out, "%s#%d  %s at %s:%d\n", marker, i, f.Name, c.name, f.Line)
+	}
+	return nil
+}
+
+func (c *Console) selectFrame(arg string) error {
+	frames, err := c.d.Stack()
+	if err != nil {
+		return err
+	}
+
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 || n >= len(frames) {
+		return fmt.Errorf("no frame %q", arg)
+	}
+
+	c.frame = n
+	fmt.Fprintf(c.out, "#%d  %s at %s:%d\n", n, frames[n].Name, c.name, frames[n].Line)
+	c.printLine(frames[n].Line, "")
+	return nil
+}
+
+func (c *Console) currentFrame() (Frame, error) {
+	frames, err := c.d.Stack()
+	if err != nil {
+		return Frame{}, err
+	}
+	return frames[c.frame], nil
+}
+
+// print prints a variable visible from the frame, or every variable bound
+// in the innermost scope of the frame.
+func (c *Console) print(arg string) error {
+	f, err := c.currentFrame()
+	if err != nil {
+		return err
+	}
+
+	if f.Env == nil {
+		return fmt.Errorf("frame has no variables yet")
+	}
+
+	if arg != "" {
+		val, ok := f.Env.Get(arg)
+		if !ok {
+			return fmt.Errorf("undefined: %s", arg)
+		}
+		fmt.Fprintf(c.out, "%s = %s\n", arg, val.Inspect())
+		return nil
+	}
+
+	for _, name := range f.Env.Names() {
+		val, _ := f.Env.Get(name)
+		fmt.Fprintf(c.out, "%s = %s\n", name, val.Inspect())
+	}
+	return nil
+}
+
+func (c *Console) eval(arg string) error {
+	val, err := c.d.Evaluate(arg, c.frame)
+	if err != nil {
+		return err
+	}
+
+	if val != nil {
+		fmt.Fprintln(c.out, val.Inspect())
+	}
+	return nil
+}
+
+func (c *Console) list(arg string) error {
+	f, err := c.currentFrame()
+	if err != nil {
+		return err
+	}
+
+	for line := f.Line - 3; line <= f.Line+3; line++ {
+		marker := "  "
+		if line == f.Line {
+			marker = "=>"
+		}
+		c.printLine(line, marker)
+	}
+	return nil
+}
+
+func (c *Console) printLine(line int, marker string) {
+	if line < 1 || line > len(c.lines) {
+		return
+	}
+	if marker == "" {
+		marker = "  "
+	}
+	fmt.Fprintf(c.out, "%s%4d  %s\n", marker, line, c.lines[line-1])
+}
+
+func (c *Console) help(arg string) error {
+	for _, cmd := range consoleCommands {
+		name := cmd.name
+		if cmd.alias != "" {
+			name += ", " + cmd.alias
+		}
+		if cmd.args != "" {
+			name += " " + cmd.args
+		}
+		fmt.Fprintf(c.out, "  %-20s %s\n", name, cmd.help)
+	}
+	fmt.Fprintf(c.out, "  %-20s %s\n", "quit, q", "stop debugging")
+	return nil
+}
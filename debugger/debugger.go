@@ -0,0 +1,431 @@
+// Package debugger runs monkey programs under control: it pauses them at
+// breakpoints and steps, and inspects the paused program's frames.
+//
+// Only the statements of the program itself are stopped at; code of imported
+// modules runs without stopping.
+//
+// A Debugger runs the program on a goroutine of its own. The controlling
+// goroutine waits for the program to stop with Wait and then inspects it and
+// resumes it. Wait may be called from another goroutine than the other
+// methods, but none of them may be called concurrently with itself.
+// SetBreakpoints and Terminate may be called from any goroutine, at any time.
+package debugger
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+// Reason tells why the program stopped.
+type Reason string
+
+const (
+	ReasonEntry      Reason = "entry"
+	ReasonBreakpoint Reason = "breakpoint"
+	ReasonStep       Reason = "step"
+	ReasonExited     Reason = "exited"
+)
+
+// Event reports that the program stopped. Result is the value the program
+// evaluated to once it has exited.
+type Event struct {
+	Reason Reason
+	Line   int
+	Result object.Object
+}
+
+// Frame is a function call in progress, or the top level of the program.
+// Line is the line being run in it and Env holds its variables.
+type Frame struct {
+	Name string
+	Line int
+	Env  *object.Environment
+}
+
+var (
+	ErrRunning = errors.New("program is running")
+	ErrExited  = errors.New("program has exited")
+)
+
+type mode int
+
+const (
+	modeContinue mode = iota
+	modeStepIn
+	modeStepOver
+	modeStepOut
+)
+
+// command resumes the program. Steps stop at the next statement run at a
+// stack depth no greater than depth.
+type command struct {
+	mode  mode
+	depth int
+}
+
+type Debugger struct {
+	program   *ast.Program
+	evaluator *evaluator.Evaluator
+
+	// statements holds the statements of the program, and lines maps each
+	// line to the first of them on it, where a breakpoint on the line stops.
+	statements map[ast.Statement]bool
+	lines      map[int]ast.Statement
+
+	mu          sync.Mutex
+	breakpoints map[ast.Statement]bool
+	state       Reason
+	started     bool
+
+	events chan Event
+	resume chan command
+
+	// terminate is closed by Terminate, and exited once the program
+	// goroutine is done.
+	terminate     chan struct{}
+	terminateOnce sync.Once
+	exited        chan struct{}
+
+	// The fields below belong to the program goroutine while it runs and to
+	// the controlling goroutine while it is stopped.
+	stack      []*Frame
+	cmd        command
+	inspecting bool
+	entry      bool
+}
+
+// New prepares program to be debugged by an Evaluator created with opts.
+func New(program *ast.Program, opts ...evaluator.Option) *Debugger {
+	d := &Debugger{
+		program:     program,
+		statements:  make(map[ast.Statement]bool),
+		lines:       make(map[int]ast.Statement),
+		breakpoints: make(map[ast.Statement]bool),
+		events:      make(chan Event),
+		resume:      make(chan command),
+		terminate:   make(chan struct{}),
+		exited:      make(chan struct{}),
+	}
+
+	d.evaluator = evaluator.New(append(opts, evaluator.WithHook(d))...)
+
+	ast.Inspect(program, func(n ast.Node) bool {
+		stmt, ok := n.(ast.Statement)
+		if !ok || n == program {
+			return true
+		}
+		if _, ok := stmt.(*ast.BlockStatement); ok {
+			return true
+		}
+
+		d.statements[stmt] = true
+
+		line := line(stmt)
+		if _, ok := d.lines[line]; !ok {
+			d.lines[line] = stmt
+		}
+		return true
+	})
+
+	return d
+}
+
+// SetBreakpoints replaces the breakpoints with ones on lines and returns the
+// lines that have a statement to stop at. It may be called while the program
+// runs.
+func (d *Debugger) SetBreakpoints(lines []int) []int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints = make(map[ast.Statement]bool)
+
+	set := []int{}
+	for _, l := range lines {
+		if stmt, ok := d.lines[l]; ok {
+			d.breakpoints[stmt] = true
+			set = append(set, l)
+		}
+	}
+	sort.Ints(set)
+
+	return set
+}
+
+// Start runs the program in env. With stopOnEntry, it stops before the first
+// statement.
+func (d *Debugger) Start(env *object.Environment, stopOnEntry bool) {
+	d.entry = stopOnEntry
+
+	d.mu.Lock()
+	d.started = true
+	d.mu.Unlock()
+
+	go func() {
+		defer close(d.exited)
+
+		ev := Event{Reason: ReasonExited, Result: d.run(env)}
+		select {
+		case d.events <- ev:
+		case <-d.terminate:
+		}
+	}()
+}
+
+// terminated is the panic unwinding the program once Terminate is called.
+type terminated struct{}
+
+// run evaluates the program in env, or returns nil if it is terminated.
+func (d *Debugger) run(env *object.Environment) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(terminated); !ok {
+				panic(r)
+			}
+		}
+	}()
+
+	return d.evaluator.Eval(d.program, env)
+}
+
+// Terminate stops the program at the statement it is stopped at, or the next
+// one it runs, and waits for it to exit. A builtin the program is blocked in,
+// such as sleep, delays it. Wait returns an exited event from then on.
+func (d *Debugger) Terminate() {
+	d.terminateOnce.Do(func() { close(d.terminate) })
+
+	d.mu.Lock()
+	started := d.started
+	d.mu.Unlock()
+
+	if started {
+		<-d.exited
+	}
+
+	d.mu.Lock()
+	d.state = ReasonExited
+	d.mu.Unlock()
+}
+
+// Wait blocks until the program stops or exits.
+func (d *Debugger) Wait() Event {
+	var ev Event
+	select {
+	case ev = <-d.events:
+	case <-d.terminate:
+		ev = Event{Reason: ReasonExited}
+	}
+
+	d.mu.Lock()
+	d.state = ev.Reason
+	d.mu.Unlock()
+
+	return ev
+}
+
+// Continue resumes the program until it reaches a breakpoint.
+func (d *Debugger) Continue() error {
+	return d.send(modeContinue)
+}
+
+// StepIn resumes the program until the next statement, including ones in
+// functions called by the current one.
+func (d *Debugger) StepIn() error {
+	return d.send(modeStepIn)
+}
+
+// StepOver resumes the program until the next statement in the current
+// function or one of its callers.
+func (d *Debugger) StepOver() error {
+	return d.send(modeStepOver)
+}
+
+// StepOut resumes the program until the current function has returned.
+func (d *Debugger) StepOut() error {
+	return d.send(modeStepOut)
+}
+
+func (d *Debugger) send(m mode) error {
+	if err := d.stopped(); err != nil {
+		return err
+	}
+
+	cmd := command{mode: m}
+	switch m {
+	case modeStepOver:
+		cmd.depth = len(d.stack)
+	case modeStepOut:
+		cmd.depth = len(d.stack) - 1
+	}
+
+	d.mu.Lock()
+	d.state = ""
+	d.mu.Unlock()
+
+	d.resume <- cmd
+	return nil
+}
+
+func (d *Debugger) stopped() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch d.state {
+	case ReasonExited:
+		return ErrExited
+	case "":
+		return ErrRunning
+	}
+	return nil
+}
+
+// Stack returns the frames of the stopped program, innermost first.
+func (d *Debugger) Stack() ([]Frame, error) {
+	if err := d.stopped(); err != nil {
+		return nil, err
+	}
+
+	frames := make([]Frame, 0, len(d.stack))
+	for i := len(d.stack) - 1; i >= 0; i-- {
+		frames = append(frames, *d.stack[i])
+	}
+	return frames, nil
+}
+
+// Evaluate evaluates src in the environment of the frame at index frame of
+// Stack. Breakpoints do not stop the evaluation.
+func (d *Debugger) Evaluate(src string, frame int) (object.Object, error) {
+	frames, err := d.Stack()
+	if err != nil {
+		return nil, err
+	}
+
+	if frame < 0 || frame >= len(frames) {
+		return nil, errors.New("no such frame")
+	}
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Diagnostics()) != 0 {
+		return nil, p.Diagnostics()[0]
+	}
+
+	env := frames[frame].Env
+	if env == nil {
+		env = object.NewEnvironment()
+	}
+
+	d.inspecting = true
+	defer func() { d.inspecting = false }()
+
+	return d.evaluator.Eval(program, env), nil
+}
+
+// Statement implements evaluator.Hook.
+func (d *Debugger) Statement(stmt ast.Statement, env *object.Environment) {
+	if d.inspecting || !d.statements[stmt] {
+		return
+	}
+
+	select {
+	case <-d.terminate:
+		panic(terminated{})
+	default:
+	}
+
+	if len(d.stack) == 0 {
+		d.stack = append(d.stack, &Frame{Name: "main"})
+	}
+
+	top := d.stack[len(d.stack)-1]
+	top.Line = line(stmt)
+	top.Env = env
+
+	reason := d.reason(stmt)
+	if reason == "" {
+		return
+	}
+
+	select {
+	case d.events <- Event{Reason: reason, Line: top.Line}:
+	case <-d.terminate:
+		panic(terminated{})
+	}
+
+	select {
+	case d.cmd = <-d.resume:
+	case <-d.terminate:
+		panic(terminated{})
+	}
+}
+
+func (d *Debugger) reason(stmt ast.Statement) Reason {
+	if d.entry {
+		d.entry = false
+		return ReasonEntry
+	}
+
+	switch d.cmd.mode {
+	case modeStepIn:
+		return ReasonStep
+	case modeStepOver, modeStepOut:
+		if len(d.stack) <= d.cmd.depth {
+			return ReasonStep
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.breakpoints[stmt] {
+		return ReasonBreakpoint
+	}
+	return ""
+}
+
+// Call implements evaluator.Hook.
+func (d *Debugger) Call(call *ast.CallExpression, fn object.Object, args []object.Object) {
+	if d.inspecting {
+		return
+	}
+
+	if _, ok := fn.(*object.Function); !ok {
+		return
+	}
+
+	if len(d.stack) != 0 {
+		d.stack[len(d.stack)-1].Line = call.Token.Pos.Line
+	}
+	d.stack = append(d.stack, &Frame{Name: call.Function.String(), Line: call.Token.Pos.Line})
+}
+
+// Return implements evaluator.Hook.
+func (d *Debugger) Return(call *ast.CallExpression, fn object.Object, result object.Object) {
+	if d.inspecting {
+		return
+	}
+
+	if _, ok := fn.(*object.Function); !ok {
+		return
+	}
+
+	d.stack = d.stack[:len(d.stack)-1]
+}
+
+func line(stmt ast.Statement) int {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		return stmt.Token.Pos.Line
+	case *ast.ReturnStatement:
+		return stmt.Token.Pos.Line
+	case *ast.ExpressionStatement:
+		return stmt.Token.Pos.Line
+	}
+	return 0
+}
@@ -0,0 +1,287 @@
+package debugger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+const script = `let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let x = add(1, 2);
+let y = add(x, 10);
+y * 2
+`
+
+func newDebugger(t *testing.T, src string) *Debugger {
+	t.Helper()
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %q", p.Errors())
+	}
+
+	return New(program)
+}
+
+func TestDebugger(t *testing.T) {
+	ignoreEnv := cmp.FilterPath(func(p cmp.Path) bool { return p.String() == "Env" }, cmp.Ignore())
+
+	t.Run("Breakpoints", func(t *testing.T) {
+		d := newDebugger(t, script)
+		d.Start(object.NewEnvironment(), true)
+
+		if diff := cmp.Diff(Event{Reason: ReasonEntry, Line: 1}, d.Wait()); diff != "" {
+			t.Fatalf("unexpected event\n%s", diff)
+		}
+
+		if diff := cmp.Diff([]int{2}, d.SetBreakpoints([]int{4, 2, 100})); diff != "" {
+			t.Errorf("unexpected breakpoints\n%s", diff)
+		}
+
+		if err := d.Continue(); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(Event{Reason: ReasonBreakpoint, Line: 2}, d.Wait()); diff != "" {
+			t.Fatalf("unexpected event\n%s", diff)
+		}
+
+		frames, err := d.Stack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Frame{{Name: "add", Line: 2}, {Name: "main", Line: 5}}
+		if diff := cmp.Diff(want, frames, ignoreEnv); diff != "" {
+			t.Errorf("unexpected stack\n%s", diff)
+		}
+
+		evaluated, err := d.Evaluate("a + b", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evaluated.Inspect() != "3" {
+			t.Errorf("unexpected value of a + b. got=%s", evaluated.Inspect())
+		}
+
+		evaluated, err = d.Evaluate("a", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if evaluated.Inspect() != "ERROR: identifier not found: a" {
+			t.Errorf("unexpected value of a in main. got=%s", evaluated.Inspect())
+		}
+
+		if _, err := d.Evaluate("let = 1", 0); err == nil || err.Error() != "1:5: expected next token to be IDENT, got = instead" {
+			t.Errorf("unexpected error. got=%v", err)
+		}
+
+		if _, err := d.Evaluate("a", 2); err == nil || err.Error() != "no such frame" {
+			t.Errorf("unexpected error. got=%v", err)
+		}
+
+		// The breakpoint is hit again by the second call.
+		d.Continue()
+		if diff := cmp.Diff(Event{Reason: ReasonBreakpoint, Line: 2}, d.Wait()); diff != "" {
+			t.Fatalf("unexpected event\n%s", diff)
+		}
+
+		d.SetBreakpoints(nil)
+		d.Continue()
+		ev := d.Wait()
+		if ev.Reason != ReasonExited || ev.Result.Inspect() != "26" {
+			t.Fatalf("unexpected event. got=%+v", ev)
+		}
+
+		if err := d.Continue(); err != ErrExited {
+			t.Errorf("unexpected error. got=%v", err)
+		}
+	})
+
+	t.Run("Stepping", func(t *testing.T) {
+		d := newDebugger(t, script)
+		d.Start(object.NewEnvironment(), true)
+		d.Wait()
+
+		steps := []struct {
+			name  string
+			step  func() error
+			line  int
+			stack []Frame
+		}{
+			{"StepOver", d.StepOver, 5, []Frame{{Name: "main", Line: 5}}},
+			{"StepIn", d.StepIn, 2, []Frame{{Name: "add", Line: 2}, {Name: "main", Line: 5}}},
+			{"StepOver", d.StepOver, 3, []Frame{{Name: "add", Line: 3}, {Name: "main", Line: 5}}},
+			{"StepOut", d.StepOut, 6, []Frame{{Name: "main", Line: 6}}},
+			{"StepOver", d.StepOver, 7, []Frame{{Name: "main", Line: 7}}},
+		}
+
+		for _, s := range steps {
+			if err := s.step(); err != nil {
+				t.Fatalf("%s failed: %s", s.name, err)
+			}
+
+			ev := d.Wait()
+			if diff := cmp.Diff(Event{Reason: ReasonStep, Line: s.line}, ev); diff != "" {
+				t.Fatalf("unexpected event after %s\n%s", s.name, diff)
+			}
+
+			frames, _ := d.Stack()
+			if diff := cmp.Diff(s.stack, frames, ignoreEnv); diff != "" {
+				t.Errorf("unexpected stack after %s\n%s", s.name, diff)
+			}
+		}
+
+		y, ok := mustStack(t, d)[0].Env.Get("y")
+		if !ok || y.Inspect() != "13" {
+			t.Errorf("unexpected value of y. got=%v", y)
+		}
+
+		d.StepOver()
+		if ev := d.Wait(); ev.Reason != ReasonExited {
+			t.Errorf("program did not exit. got=%+v", ev)
+		}
+	})
+
+	t.Run("Terminate", func(t *testing.T) {
+		d := newDebugger(t, script)
+		d.SetBreakpoints([]int{2})
+		d.Start(object.NewEnvironment(), false)
+		d.Wait()
+
+		d.Terminate()
+		select {
+		case <-d.exited:
+		default:
+			t.Fatal("program goroutine still running")
+		}
+
+		if ev := d.Wait(); ev.Reason != ReasonExited || ev.Result != nil {
+			t.Errorf("unexpected event. got=%+v", ev)
+		}
+		if err := d.Continue(); err != ErrExited {
+			t.Errorf("unexpected error. got=%v", err)
+		}
+	})
+
+	t.Run("TerminateConcurrently", func(t *testing.T) {
+		d := newDebugger(t, script)
+		d.SetBreakpoints([]int{2})
+
+		done := make(chan struct{})
+		go func() {
+			d.Terminate()
+			close(done)
+		}()
+		d.Start(object.NewEnvironment(), false)
+		<-done
+
+		if ev := d.Wait(); ev.Reason != ReasonExited {
+			t.Errorf("unexpected event. got=%+v", ev)
+		}
+	})
+
+	t.Run("TerminateBeforeStart", func(t *testing.T) {
+		d := newDebugger(t, script)
+		d.Terminate()
+
+		if ev := d.Wait(); ev.Reason != ReasonExited {
+			t.Errorf("unexpected event. got=%+v", ev)
+		}
+	})
+
+	t.Run("Running", func(t *testing.T) {
+		d := newDebugger(t, script)
+
+		if err := d.StepIn(); err != ErrRunning {
+			t.Errorf("unexpected error. got=%v", err)
+		}
+
+		d.Start(object.NewEnvironment(), false)
+		if ev := d.Wait(); ev.Reason != ReasonExited {
+			t.Errorf("program did not run to the end. got=%+v", ev)
+		}
+	})
+}
+
+func mustStack(t *testing.T, d *Debugger) []Frame {
+	t.Helper()
+
+	frames, err := d.Stack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frames
+}
+
+func TestConsole(t *testing.T) {
+	input := `break 4
+break 2
+bt
+c
+bt
+p
+p sum
+p x
+frame 1
+e add(x, 1)
+list
+n
+n
+o
+clear 2
+clear 2
+nope
+c
+`
+	d := newDebugger(t, script)
+
+	var out strings.Builder
+	c := NewConsole(d, "add.monkey", script, strings.NewReader(input), &out)
+	if err := c.Run(object.NewEnvironment()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `stopped at add.monkey:1 (entry)
+     1  let add = fn(a, b) {
+(debug) no statement on line 4
+(debug) breakpoint at add.monkey:2
+(debug) *#0  main at add.monkey:1
+(debug) stopped at add.monkey:2 (breakpoint)
+     2    let sum = a + b;
+(debug) *#0  add at add.monkey:2
+ #1  main at add.monkey:5
+(debug) a = 1
+b = 2
+(debug) undefined: sum
+(debug) undefined: x
+(debug) #1  main at add.monkey:5
+     5  let x = add(1, 2);
+(debug) ERROR: identifier not found: x
+(debug)      2    let sum = a + b;
+     3    sum
+     4  };
+=>   5  let x = add(1, 2);
+     6  let y = add(x, 10);
+     7  y * 2
+(debug) stopped at add.monkey:3 (step)
+     3    sum
+(debug) stopped at add.monkey:6 (step)
+     6  let y = add(x, 10);
+(debug) stopped at add.monkey:2 (breakpoint)
+     2    let sum = a + b;
+(debug) (debug) no breakpoint on line 2
+(debug) unknown command: nope (try help)
+(debug) program exited: 26
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("unexpected output\n%s", diff)
+	}
+}
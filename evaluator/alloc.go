@@ -0,0 +1,144 @@
+package evaluator
+
+import (
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/object"
+)
+
+// The integers from minSmallInteger to maxSmallInteger are allocated once, as
+// values are immutable: loop counters, indexes and lengths mostly fall in
+// this range.
+const (
+	minSmallInteger = -128
+	maxSmallInteger = 1024
+)
+
+var smallIntegers = func() []*object.Integer {
+	ints := make([]*object.Integer, maxSmallInteger-minSmallInteger+1)
+	for i := range ints {
+		ints[i] = &object.Integer{Value: int64(i + minSmallInteger)}
+	}
+	return ints
+}()
+
+// newInteger returns an Integer holding value, shared if it is small.
+func newInteger(value int64) *object.Integer {
+	if minSmallInteger <= value && value <= maxSmallInteger {
+		return smallIntegers[value-minSmallInteger]
+	}
+	return &object.Integer{Value: value}
+}
+
+// isShared reports whether obj is one of the values allocated once for every
+// evaluation.
+func isShared(obj object.Object) bool {
+	switch obj {
+	case NULL, TRUE, FALSE:
+		return true
+	}
+
+	i, ok := obj.(*object.Integer)
+	return ok && minSmallInteger <= i.Value && i.Value <= maxSmallInteger && smallIntegers[i.Value-minSmallInteger] == i
+}
+
+// functionEnv returns the environment of a call of fn with args, reusing
+// one released by an earlier call when there is one.
+func (e *Evaluator) functionEnv(fn *object.Function, args []object.Object) *object.Environment {
+	var env *object.Environment
+	if n := len(e.free); n > 0 {
+		env = e.free[n-1]
+		e.free = e.free[:n-1]
+		env.Reset(fn.Env, fn.Locals)
+	} else {
+		env = object.NewFunctionEnvironment(fn.Env, fn.Locals)
+		e.allocs++
+	}
+
+	for paramIdx, param := range fn.Parameters {
+		if param.Scope == ast.ScopeLocal {
+			env.SetSlot(param.Slot, args[paramIdx])
+		} else {
+			env.Set(param.Value, args[paramIdx])
+		}
+	}
+
+	return env
+}
+
+// release makes env, the environment of a call of fn that has returned,
+// available to later calls if nothing can refer to it anymore. Only the
+// closures created by the call could, so it keeps the environments of
+// functions that are not leaves, and all of them when a Hook, which is
+// passed environments, is set.
+func (e *Evaluator) release(env *object.Environment, fn *object.Function) {
+	if e.hook != nil || !fn.Leaf {
+		return
+	}
+
+	env.Reset(nil, nil)
+	e.free = append(e.free, env)
+}
+
+// evalStringLiteral returns the value of sl, allocated the first time it is
+// evaluated in the program only.
+func (e *Evaluator) evalStringLiteral(sl *ast.StringLiteral) object.Object {
+	if str, ok := e.strings[sl]; ok {
+		return str
+	}
+
+	str := &object.String{Value: sl.Value}
+	e.strings[sl] = str
+	return e.allocated(str)
+}
+
+// evalCall applies function to the arguments of node, evaluated onto the
+// argument stack instead of a slice of their own, and pops them once it
+// returns. Functions copy their arguments to the environment of the call
+// and builtins do not keep them, so nothing refers to them afterwards.
+//
+// Calls in tail position return a tailCall whose arguments are popped
+// already, which is safe as the trampoline copies them before evaluating
+// anything else. Builtins, which may evaluate functions before they are done
+// with their arguments, are called directly instead.
+func (e *Evaluator) evalCall(node *ast.CallExpression, function object.Object, env *object.Environment) object.Object {
+	base := len(e.stack)
+
+	for _, exp := range node.Arguments {
+		evaluated := e.Eval(exp, env)
+		if isError(evaluated) {
+			e.pop(base)
+			return evaluated
+		}
+		e.stack = append(e.stack, evaluated)
+	}
+	args := e.stack[base:len(e.stack):len(e.stack)]
+
+	e.call = node
+	if _, ok := function.(*object.Function); ok && node.Tail {
+		e.stack = e.stack[:base]
+		e.tail = tailCall{call: node, fn: function, args: args}
+		return &e.tail
+	}
+
+	result := e.callFunction(function, args)
+	e.pop(base)
+	return result
+}
+
+// endProgram drops the values of the string literals evaluated once the
+// program is done, unless it is a module imported by another, so that the
+// Evaluator does not keep every program it evaluates.
+func (e *Evaluator) endProgram() {
+	e.programs--
+	if e.programs == 0 && len(e.strings) != 0 {
+		e.strings = make(map[*ast.StringLiteral]*object.String)
+	}
+}
+
+// pop removes the values above base from the argument stack.
+func (e *Evaluator) pop(base int) {
+	for i := base; i < len(e.stack); i++ {
+		e.stack[i] = nil
+	}
+	e.stack = e.stack[:base]
+}
@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+var benchmarks = []struct {
+	name  string
+	input string
+}{
+	{"Fib", `let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(20)`},
+	{"Strings", `let build = fn(i, s) { if (i == 0) { s } else { build(i - 1, s + "ab" + upper("c")) } };
+len(build(2000, ""))`},
+	{"Closures", `let counter = fn(start) {
+  let step = 1;
+  fn(n) { start + n * step }
+};
+let sum = fn(f, i, acc) { if (i == 0) { acc } else { sum(f, i - 1, acc + f(i)) } };
+sum(counter(10), 20000, 0)`},
+	{"Locals", `let work = fn(a, b) {
+  let c = a + b;
+  let d = c * a;
+  let e = d - b;
+  let inner = fn(x) { x + a + b + c + d + e };
+  inner(1) + inner(2)
+};
+let loop = fn(i, acc) { if (i == 0) { acc } else { loop(i - 1, acc + work(i, 2)) } };
+loop(10000, 0)`},
+}
+
+func BenchmarkEval(b *testing.B) {
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			program := parser.New(lexer.New(bm.input)).ParseProgram()
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if result := New().Eval(program, object.NewEnvironment()); isError(result) {
+					b.Fatal(result.Inspect())
+				}
+			}
+		})
+	}
+}
@@ -1,20 +1,152 @@
 package evaluator
 
-import "github.com/yagihash/monkey/object"
+import (
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/yagihash/monkey/object"
+)
 
 var builtins = map[string]*object.Builtin{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			if err := checkArgs("len", args, 1); err != nil {
+				return err
 			}
 
 			switch arg := args[0].(type) {
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return newInteger(int64(utf8.RuneCountInString(arg.Value)))
+			case *object.Array:
+				return newInteger(int64(len(arg.Elements)))
+			case *object.Hash:
+				return newInteger(int64(len(arg.Pairs)))
 			default:
-				return newError("argument to `len` not supported, got=%s", args[0].Type())
+				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
 		},
 	},
+	"split":       {Fn: builtinSplit},
+	"join":        {Fn: builtinJoin},
+	"upper":       {Fn: builtinUpper},
+	"lower":       {Fn: builtinLower},
+	"trim":        {Fn: builtinTrim},
+	"replace":     {Fn: builtinReplace},
+	"contains":    {Fn: builtinContains},
+	"starts_with": {Fn: builtinStartsWith},
+	"ends_with":   {Fn: builtinEndsWith},
+	"index_of":    {Fn: builtinIndexOf},
+	"repeat":      {Fn: builtinRepeat},
+	"substr":      {Fn: builtinSubstr},
+	"abs":         {Fn: builtinAbs},
+	"min":         {Fn: builtinMin},
+	"max":         {Fn: builtinMax},
+	"pow":         {Fn: builtinPow},
+	"sqrt":        {Fn: builtinSqrt},
+	"gcd":         {Fn: builtinGcd},
+	"clamp":       {Fn: builtinClamp},
+	"floor":       {Fn: builtinFloor},
+	"ceil":        {Fn: builtinCeil},
+	"round":       {Fn: builtinRound},
+
+	"json_parse":     {Fn: builtinJSONParse},
+	"json_stringify": {Fn: builtinJSONStringify},
+
+	"duration":    {Fn: builtinDuration},
+	"format_time": {Fn: builtinFormatTime},
+	"parse_time":  {Fn: builtinParseTime},
+
+	"channel": {Fn: builtinChannel},
+	"send":    {Fn: builtinSend},
+	"recv":    {Fn: builtinRecv},
+	"close":   {Fn: builtinClose},
+}
+
+// boundBuiltins returns the builtins that depend on the state of e.
+func (e *Evaluator) boundBuiltins() map[string]*object.Builtin {
+	return map[string]*object.Builtin{
+		"re_match":     {Fn: e.builtinReMatch},
+		"re_find_all":  {Fn: e.builtinReFindAll},
+		"re_replace":   {Fn: e.builtinReReplace},
+		"re_split":     {Fn: e.builtinReSplit},
+		"now":          {Fn: e.builtinNow},
+		"sleep":        {Fn: e.builtinSleep},
+		"read_file":    {Fn: e.builtinReadFile},
+		"write_file":   {Fn: e.builtinWriteFile},
+		"list_dir":     {Fn: e.builtinListDir},
+		"exists":       {Fn: e.builtinExists},
+		"puts":         {Fn: e.builtinPuts},
+		"print":        {Fn: e.builtinPrint},
+		"eprint":       {Fn: e.builtinEprint},
+		"assert":       {Fn: e.builtinAssert},
+		"assert_eq":    {Fn: e.builtinAssertEq},
+		"assert_error": {Fn: e.builtinAssertError},
+		"spawn":        {Fn: e.builtinSpawn},
+		"select":       {Fn: e.builtinSelect},
+	}
+}
+
+// BuiltinNames returns the sorted names of the builtins available to e.
+func (e *Evaluator) BuiltinNames() []string {
+	names := make([]string, 0, len(e.builtins))
+	for name := range e.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var constants = map[string]object.Object{
+	"PI": &object.Float{Value: math.Pi},
+	"E":  &object.Float{Value: math.E},
+}
+
+func checkArgs(name string, args []object.Object, want int) *object.Error {
+	if len(args) != want {
+		return newError("wrong number of arguments to `%s`. got=%d, want=%d", name, len(args), want)
+	}
+	return nil
+}
+
+func checkArgsRange(name string, args []object.Object, min, max int) *object.Error {
+	if len(args) < min || len(args) > max {
+		return newError("wrong number of arguments to `%s`. got=%d, want=%d..%d", name, len(args), min, max)
+	}
+	return nil
+}
+
+func argTypeError(name string, pos int, want object.ObjectType, got object.Object) *object.Error {
+	return newError("argument %d to `%s` must be %s, got %s", pos+1, name, want, got.Type())
+}
+
+func stringArg(name string, args []object.Object, pos int) (string, *object.Error) {
+	s, ok := args[pos].(*object.String)
+	if !ok {
+		return "", argTypeError(name, pos, object.StringObj, args[pos])
+	}
+	return s.Value, nil
+}
+
+func integerArg(name string, args []object.Object, pos int) (int64, *object.Error) {
+	i, ok := args[pos].(*object.Integer)
+	if !ok {
+		return 0, argTypeError(name, pos, object.IntegerObj, args[pos])
+	}
+	return i.Value, nil
+}
+
+func numberArg(name string, args []object.Object, pos int) (float64, *object.Error) {
+	if !isNumber(args[pos]) {
+		return 0, newError("argument %d to `%s` must be INTEGER or FLOAT, got %s", pos+1, name, args[pos].Type())
+	}
+	return toFloat(args[pos]), nil
+}
+
+func stringsToArray(strs []string) *object.Array {
+	elements := make([]object.Object, len(strs))
+	for i, s := range strs {
+		elements[i] = &object.String{Value: s}
+	}
+	return &object.Array{Elements: elements}
 }
@@ -0,0 +1,163 @@
+package evaluator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/object"
+)
+
+// The assertion builtins return NULL when the assertion holds and otherwise
+// an error marked as an assertion, whose message starts with the position of
+// the call and ends with the optional message passed to the builtin.
+
+func (e *Evaluator) builtinAssert(args ...object.Object) object.Object {
+	call := e.call
+	if err := checkArgsRange("assert", args, 1, 2); err != nil {
+		return err
+	}
+
+	message, err := messageArg("assert", args, 1)
+	if err != nil {
+		return err
+	}
+
+	if isTruthy(args[0]) {
+		return NULL
+	}
+	return assertionError(call, message, "assert failed: got %s", describe(args[0]))
+}
+
+func (e *Evaluator) builtinAssertEq(args ...object.Object) object.Object {
+	call := e.call
+	if err := checkArgsRange("assert_eq", args, 2, 3); err != nil {
+		return err
+	}
+
+	message, err := messageArg("assert_eq", args, 2)
+	if err != nil {
+		return err
+	}
+
+	if objectsEqual(args[0], args[1]) {
+		return NULL
+	}
+	return assertionError(call, message, "assert_eq failed: got %s, want %s", describe(args[0]), describe(args[1]))
+}
+
+// builtinAssertError calls a function without arguments and checks that it
+// fails, with an error containing the optional substring.
+func (e *Evaluator) builtinAssertError(args ...object.Object) object.Object {
+	call := e.call
+	if err := checkArgsRange("assert_error", args, 1, 2); err != nil {
+		return err
+	}
+
+	want, err := messageArg("assert_error", args, 1)
+	if err != nil {
+		return err
+	}
+
+	switch fn := args[0].(type) {
+	case *object.Function:
+		if len(fn.Parameters) != 0 {
+			return newError("function passed to `assert_error` must not take parameters")
+		}
+	case *object.Builtin:
+	default:
+		return argTypeError("assert_error", 0, object.FunctionObj, args[0])
+	}
+
+	result, ok := e.callFunction(args[0], nil).(*object.Error)
+	switch {
+	case !ok:
+		return assertionError(call, "", "assert_error failed: got no error")
+	case !strings.Contains(result.Message, want):
+		return assertionError(call, "", "assert_error failed: got error %q, want one containing %q", result.Message, want)
+	}
+	return NULL
+}
+
+// messageArg returns the optional string argument at pos.
+func messageArg(name string, args []object.Object, pos int) (string, *object.Error) {
+	if pos >= len(args) {
+		return "", nil
+	}
+	return stringArg(name, args, pos)
+}
+
+func assertionError(call *ast.CallExpression, message, format string, a ...interface{}) *object.Error {
+	msg := fmt.Sprintf(format, a...)
+	if message != "" {
+		msg += ": " + message
+	}
+
+	if call != nil {
+		pos := call.Token.Pos
+		if ident, ok := call.Function.(*ast.Identifier); ok {
+			pos = ident.Token.Pos
+		}
+		msg = pos.String() + ": " + msg
+	}
+
+	return &object.Error{Message: msg, Assertion: true}
+}
+
+// describe formats obj for an assertion message, quoting strings so that they
+// can be told apart from other values.
+func describe(obj object.Object) string {
+	if s, ok := obj.(*object.String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return obj.Inspect()
+}
+
+// objectsEqual reports whether a and b are equal values. Numbers are equal
+// when their values are, whether integers or floats; arrays and hashes when
+// their elements are.
+func objectsEqual(a, b object.Object) bool {
+	if a, ok := a.(*object.Integer); ok {
+		if b, ok := b.(*object.Integer); ok {
+			return a.Value == b.Value
+		}
+	}
+	if isNumber(a) && isNumber(b) {
+		return toFloat(a) == toFloat(b)
+	}
+
+	switch a := a.(type) {
+	case *object.String:
+		b, ok := b.(*object.String)
+		return ok && a.Value == b.Value
+	case *object.Array:
+		b, ok := b.(*object.Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !objectsEqual(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		b, ok := b.(*object.Hash)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for key, pa := range a.Pairs {
+			pb, ok := b.Pairs[key]
+			if !ok || !objectsEqual(pa.Value, pb.Value) {
+				return false
+			}
+		}
+		return true
+	case *object.Error:
+		b, ok := b.(*object.Error)
+		return ok && a.Message == b.Message
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,177 @@
+package evaluator
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/yagihash/monkey/object"
+)
+
+// Channels pass values between spawned functions. Receiving from a closed
+// channel returns null once the values sent before it was closed have been
+// received.
+
+func builtinChannel(args ...object.Object) object.Object {
+	if err := checkArgsRange("channel", args, 0, 1); err != nil {
+		return err
+	}
+
+	var capacity int64
+	if len(args) == 1 {
+		var err *object.Error
+		if capacity, err = integerArg("channel", args, 0); err != nil {
+			return err
+		}
+		if capacity < 0 {
+			return newError("negative capacity passed to `channel`: %d", capacity)
+		}
+	}
+
+	return &object.Channel{Chan: make(chan object.Object, capacity)}
+}
+
+func builtinSend(args ...object.Object) (result object.Object) {
+	if err := checkArgs("send", args, 2); err != nil {
+		return err
+	}
+
+	ch, err := channelArg("send", args, 0)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if recover() != nil {
+			result = newError("send on closed channel")
+		}
+	}()
+
+	object.Share(args[1])
+	ch <- args[1]
+
+	return NULL
+}
+
+func builtinRecv(args ...object.Object) object.Object {
+	if err := checkArgs("recv", args, 1); err != nil {
+		return err
+	}
+
+	ch, err := channelArg("recv", args, 0)
+	if err != nil {
+		return err
+	}
+
+	val, ok := <-ch
+	if !ok {
+		return NULL
+	}
+	return val
+}
+
+func builtinClose(args ...object.Object) (result object.Object) {
+	if err := checkArgs("close", args, 1); err != nil {
+		return err
+	}
+
+	ch, err := channelArg("close", args, 0)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if recover() != nil {
+			result = newError("close of closed channel")
+		}
+	}()
+
+	close(ch)
+
+	return NULL
+}
+
+// builtinSelect waits until one of the cases of its first argument can
+// proceed and returns its index and the value received, or null for sends.
+// A case is either a channel to receive from or an array of a channel and a
+// value to send to it. With a timeout in milliseconds as second argument, it
+// returns -1 and null if no case could proceed in time, as told by e's clock.
+func (e *Evaluator) builtinSelect(args ...object.Object) (result object.Object) {
+	if err := checkArgsRange("select", args, 1, 2); err != nil {
+		return err
+	}
+
+	cases, ok := args[0].(*object.Array)
+	if !ok {
+		return argTypeError("select", 0, object.ArrayObj, args[0])
+	}
+
+	selectCases := make([]reflect.SelectCase, 0, len(cases.Elements)+1)
+	for i, c := range cases.Elements {
+		sc, err := selectCase(i, c)
+		if err != nil {
+			return err
+		}
+		selectCases = append(selectCases, sc)
+	}
+
+	if len(args) == 1 && len(selectCases) == 0 {
+		return newError("no cases passed to `select`")
+	}
+
+	if len(args) == 2 {
+		ms, err := integerArg("select", args, 1)
+		if err != nil {
+			return err
+		}
+		if ms < 0 {
+			return newError("negative timeout passed to `select`: %d", ms)
+		}
+		if ms > maxDuration {
+			return newError("timeout passed to `select` out of range: %d", ms)
+		}
+
+		timeout := e.clock.After(time.Duration(ms) * time.Millisecond)
+		selectCases = append(selectCases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timeout)})
+	}
+
+	defer func() {
+		if recover() != nil {
+			result = newError("send on closed channel")
+		}
+	}()
+
+	chosen, val, ok := reflect.Select(selectCases)
+	if chosen == len(cases.Elements) {
+		return &object.Array{Elements: []object.Object{newInteger(-1), NULL}}
+	}
+
+	received := object.Object(NULL)
+	if ok && selectCases[chosen].Dir == reflect.SelectRecv {
+		received = val.Interface().(object.Object)
+	}
+	return &object.Array{Elements: []object.Object{newInteger(int64(chosen)), received}}
+}
+
+func selectCase(i int, c object.Object) (reflect.SelectCase, *object.Error) {
+	switch c := c.(type) {
+	case *object.Channel:
+		return reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.Chan)}, nil
+	case *object.Array:
+		if len(c.Elements) != 2 {
+			break
+		}
+		if ch, ok := c.Elements[0].(*object.Channel); ok {
+			object.Share(c.Elements[1])
+			return reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(ch.Chan), Send: reflect.ValueOf(c.Elements[1])}, nil
+		}
+	}
+	return reflect.SelectCase{}, newError("case %d passed to `select` must be CHANNEL or [CHANNEL, value], got %s", i, c.Type())
+}
+
+func channelArg(name string, args []object.Object, pos int) (chan object.Object, *object.Error) {
+	ch, ok := args[pos].(*object.Channel)
+	if !ok {
+		return nil, argTypeError(name, pos, object.ChannelObj, args[pos])
+	}
+	return ch.Chan, nil
+}
@@ -0,0 +1,127 @@
+package evaluator
+
+import (
+	"io/fs"
+	"path"
+
+	"github.com/yagihash/monkey/object"
+)
+
+func fileArg(name string, args []object.Object, pos int) (string, *object.Error) {
+	p, err := stringArg(name, args, pos)
+	if err != nil {
+		return "", err
+	}
+
+	cleaned := path.Clean(p)
+	if !fs.ValidPath(cleaned) {
+		return "", newError("invalid path passed to `%s`: %q", name, p)
+	}
+
+	return cleaned, nil
+}
+
+func (e *Evaluator) readableFS(name string) (fs.FS, *object.Error) {
+	if e.fsys == nil {
+		return nil, newError("file system access is disabled for `%s`", name)
+	}
+	return e.fsys, nil
+}
+
+func (e *Evaluator) builtinReadFile(args ...object.Object) object.Object {
+	if err := checkArgs("read_file", args, 1); err != nil {
+		return err
+	}
+
+	fsys, err := e.readableFS("read_file")
+	if err != nil {
+		return err
+	}
+
+	name, err := fileArg("read_file", args, 0)
+	if err != nil {
+		return err
+	}
+
+	data, readErr := fs.ReadFile(fsys, name)
+	if readErr != nil {
+		return newError("`read_file` failed: %s", readErr)
+	}
+
+	return &object.String{Value: string(data)}
+}
+
+func (e *Evaluator) builtinWriteFile(args ...object.Object) object.Object {
+	if err := checkArgs("write_file", args, 2); err != nil {
+		return err
+	}
+
+	if e.wfs == nil {
+		return newError("file system access is disabled for `write_file`")
+	}
+
+	name, err := fileArg("write_file", args, 0)
+	if err != nil {
+		return err
+	}
+
+	content, err := stringArg("write_file", args, 1)
+	if err != nil {
+		return err
+	}
+
+	if writeErr := e.wfs.WriteFile(name, []byte(content), 0644); writeErr != nil {
+		return newError("`write_file` failed: %s", writeErr)
+	}
+
+	return NULL
+}
+
+func (e *Evaluator) builtinListDir(args ...object.Object) object.Object {
+	if err := checkArgsRange("list_dir", args, 0, 1); err != nil {
+		return err
+	}
+
+	fsys, err := e.readableFS("list_dir")
+	if err != nil {
+		return err
+	}
+
+	name := "."
+	if len(args) == 1 {
+		if name, err = fileArg("list_dir", args, 0); err != nil {
+			return err
+		}
+	}
+
+	entries, readErr := fs.ReadDir(fsys, name)
+	if readErr != nil {
+		return newError("`list_dir` failed: %s", readErr)
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+
+	return stringsToArray(names)
+}
+
+func (e *Evaluator) builtinExists(args ...object.Object) object.Object {
+	if err := checkArgs("exists", args, 1); err != nil {
+		return err
+	}
+
+	fsys, err := e.readableFS("exists")
+	if err != nil {
+		return err
+	}
+
+	name, err := fileArg("exists", args, 0)
+	if err != nil {
+		return err
+	}
+
+	_, statErr := fs.Stat(fsys, name)
+	return nativeBoolToBooleanObject(statErr == nil)
+}
@@ -0,0 +1,178 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/yagihash/monkey/object"
+)
+
+func builtinJSONParse(args ...object.Object) object.Object {
+	if err := checkArgs("json_parse", args, 1); err != nil {
+		return err
+	}
+
+	s, err := stringArg("json_parse", args, 0)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return jsonParseError(err, len(s))
+	}
+
+	offset := dec.InputOffset()
+	var extra interface{}
+	if err := dec.Decode(&extra); err != io.EOF {
+		return newError("invalid JSON passed to `json_parse` at offset %d: unexpected data after top-level value", offset)
+	}
+
+	return fromJSON(v)
+}
+
+func jsonParseError(err error, end int) *object.Error {
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// Offset counts the bytes read so far, including the offending one.
+		offset := syntaxErr.Offset - 1
+		if offset < 0 {
+			offset = 0
+		}
+		return newError("invalid JSON passed to `json_parse` at offset %d: %s", offset, syntaxErr)
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return newError("invalid JSON passed to `json_parse` at offset %d: unexpected end of input", end)
+	default:
+		return newError("invalid JSON passed to `json_parse`: %s", err)
+	}
+}
+
+func fromJSON(v interface{}) object.Object {
+	switch v := v.(type) {
+	case nil:
+		return NULL
+	case bool:
+		return nativeBoolToBooleanObject(v)
+	case string:
+		return &object.String{Value: v}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return newInteger(i)
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return newError("number passed to `json_parse` out of range: %s", v)
+		}
+		return &object.Float{Value: f}
+	case []interface{}:
+		elements := make([]object.Object, len(v))
+		for i, el := range v {
+			elements[i] = fromJSON(el)
+			if isError(elements[i]) {
+				return elements[i]
+			}
+		}
+		return &object.Array{Elements: elements}
+	case map[string]interface{}:
+		pairs := make(map[object.HashKey]object.HashPair, len(v))
+		for k, el := range v {
+			value := fromJSON(el)
+			if isError(value) {
+				return value
+			}
+			key := &object.String{Value: k}
+			pairs[key.HashKey()] = object.HashPair{Key: key, Value: value}
+		}
+		return &object.Hash{Pairs: pairs}
+	default:
+		return newError("unexpected JSON value %T", v)
+	}
+}
+
+func builtinJSONStringify(args ...object.Object) object.Object {
+	if err := checkArgsRange("json_stringify", args, 1, 2); err != nil {
+		return err
+	}
+
+	indent := ""
+	if len(args) == 2 {
+		switch arg := args[1].(type) {
+		case *object.Integer:
+			if arg.Value < 0 || arg.Value > 10 {
+				return newError("indent passed to `json_stringify` out of range: %d", arg.Value)
+			}
+			indent = strings.Repeat(" ", int(arg.Value))
+		case *object.String:
+			indent = arg.Value
+		default:
+			return newError("argument 2 to `json_stringify` must be INTEGER or STRING, got %s", arg.Type())
+		}
+	}
+
+	v, err := toJSON(args[0])
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	if err := enc.Encode(v); err != nil {
+		return newError("could not encode value passed to `json_stringify`: %s", err)
+	}
+
+	return &object.String{Value: strings.TrimSuffix(buf.String(), "\n")}
+}
+
+func toJSON(obj object.Object) (interface{}, *object.Error) {
+	switch obj := obj.(type) {
+	case *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return nil, newError("value %s cannot be encoded by `json_stringify`", obj.Inspect())
+		}
+		return json.Number(obj.Inspect()), nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Array:
+		elements := make([]interface{}, len(obj.Elements))
+		for i, el := range obj.Elements {
+			v, err := toJSON(el)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = v
+		}
+		return elements, nil
+	case *object.Hash:
+		m := make(map[string]interface{}, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return nil, newError("hash key of type %s cannot be encoded by `json_stringify`", pair.Key.Type())
+			}
+			v, err := toJSON(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		return m, nil
+	default:
+		return nil, newError("value of type %s cannot be encoded by `json_stringify`", obj.Type())
+	}
+}
@@ -0,0 +1,222 @@
+package evaluator
+
+import (
+	"math"
+
+	"github.com/yagihash/monkey/object"
+)
+
+func builtinAbs(args ...object.Object) object.Object {
+	if err := checkArgs("abs", args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		if arg.Value == math.MinInt64 {
+			return newError("result of `abs` out of integer range: %d", arg.Value)
+		}
+		if arg.Value < 0 {
+			return newInteger(-arg.Value)
+		}
+		return arg
+	case *object.Float:
+		return &object.Float{Value: math.Abs(arg.Value)}
+	default:
+		return newError("argument 1 to `abs` must be INTEGER or FLOAT, got %s", arg.Type())
+	}
+}
+
+func builtinMin(args ...object.Object) object.Object {
+	return pickNumber("min", args, func(a, b float64) bool { return a < b })
+}
+
+func builtinMax(args ...object.Object) object.Object {
+	return pickNumber("max", args, func(a, b float64) bool { return a > b })
+}
+
+func pickNumber(name string, args []object.Object, better func(a, b float64) bool) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments to `%s`. got=0, want=1 or more", name)
+	}
+
+	best := args[0]
+	bestVal, err := numberArg(name, args, 0)
+	if err != nil {
+		return err
+	}
+
+	for i := 1; i < len(args); i++ {
+		val, err := numberArg(name, args, i)
+		if err != nil {
+			return err
+		}
+
+		if better(val, bestVal) {
+			best, bestVal = args[i], val
+		}
+	}
+
+	return best
+}
+
+func builtinPow(args ...object.Object) object.Object {
+	if err := checkArgs("pow", args, 2); err != nil {
+		return err
+	}
+
+	base, err := numberArg("pow", args, 0)
+	if err != nil {
+		return err
+	}
+
+	exp, err := numberArg("pow", args, 1)
+	if err != nil {
+		return err
+	}
+
+	intBase, baseOk := args[0].(*object.Integer)
+	intExp, expOk := args[1].(*object.Integer)
+	if !baseOk || !expOk || intExp.Value < 0 {
+		return &object.Float{Value: math.Pow(base, exp)}
+	}
+
+	result, b, e := int64(1), intBase.Value, intExp.Value
+	for e > 0 {
+		ok := true
+		if e&1 == 1 {
+			result, ok = multiply(result, b)
+		}
+		if ok && e > 1 {
+			b, ok = multiply(b, b)
+		}
+		if !ok {
+			return newError("result of `pow` out of integer range: %d^%d", intBase.Value, intExp.Value)
+		}
+		e >>= 1
+	}
+
+	return newInteger(result)
+}
+
+// multiply returns a * b, and false if it overflows.
+func multiply(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+
+	c := a * b
+	return c, c/b == a
+}
+
+func builtinSqrt(args ...object.Object) object.Object {
+	if err := checkArgs("sqrt", args, 1); err != nil {
+		return err
+	}
+
+	x, err := numberArg("sqrt", args, 0)
+	if err != nil {
+		return err
+	}
+
+	if x < 0 {
+		return newError("negative argument passed to `sqrt`: %s", args[0].Inspect())
+	}
+
+	return &object.Float{Value: math.Sqrt(x)}
+}
+
+func builtinGcd(args ...object.Object) object.Object {
+	if err := checkArgs("gcd", args, 2); err != nil {
+		return err
+	}
+
+	a, err := integerArg("gcd", args, 0)
+	if err != nil {
+		return err
+	}
+
+	b, err := integerArg("gcd", args, 1)
+	if err != nil {
+		return err
+	}
+
+	x, y := a, b
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	// The gcd of math.MinInt64 and a multiple of it is 2^63, which is not
+	// an integer.
+	if a == math.MinInt64 {
+		return newError("result of `gcd` out of integer range: gcd(%d, %d)", x, y)
+	}
+	if a < 0 {
+		a = -a
+	}
+
+	return newInteger(a)
+}
+
+func builtinClamp(args ...object.Object) object.Object {
+	if err := checkArgs("clamp", args, 3); err != nil {
+		return err
+	}
+
+	var vals [3]float64
+	for i := range vals {
+		val, err := numberArg("clamp", args, i)
+		if err != nil {
+			return err
+		}
+		vals[i] = val
+	}
+
+	x, lo, hi := vals[0], vals[1], vals[2]
+	if lo > hi {
+		return newError("lower bound passed to `clamp` is greater than upper bound: %s > %s", args[1].Inspect(), args[2].Inspect())
+	}
+
+	switch {
+	case x < lo:
+		return args[1]
+	case x > hi:
+		return args[2]
+	default:
+		return args[0]
+	}
+}
+
+func builtinFloor(args ...object.Object) object.Object {
+	return roundNumber("floor", args, math.Floor)
+}
+
+func builtinCeil(args ...object.Object) object.Object {
+	return roundNumber("ceil", args, math.Ceil)
+}
+
+func builtinRound(args ...object.Object) object.Object {
+	return roundNumber("round", args, math.Round)
+}
+
+func roundNumber(name string, args []object.Object, fn func(float64) float64) object.Object {
+	if err := checkArgs(name, args, 1); err != nil {
+		return err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		return arg
+	case *object.Float:
+		rounded := fn(arg.Value)
+		if math.IsNaN(rounded) || rounded < math.MinInt64 || rounded >= math.MaxInt64 {
+			return newError("argument to `%s` out of integer range: %s", name, arg.Inspect())
+		}
+		return newInteger(int64(rounded))
+	default:
+		return newError("argument 1 to `%s` must be INTEGER or FLOAT, got %s", name, arg.Type())
+	}
+}
@@ -0,0 +1,41 @@
+package evaluator
+
+import (
+	"io"
+	"strings"
+
+	"github.com/yagihash/monkey/object"
+)
+
+func (e *Evaluator) builtinPuts(args ...object.Object) object.Object {
+	var out strings.Builder
+	for _, arg := range args {
+		out.WriteString(arg.Inspect())
+		out.WriteString("\n")
+	}
+
+	return writeOutput("puts", e.stdout, out.String())
+}
+
+func (e *Evaluator) builtinPrint(args ...object.Object) object.Object {
+	return writeOutput("print", e.stdout, joinInspected(args))
+}
+
+func (e *Evaluator) builtinEprint(args ...object.Object) object.Object {
+	return writeOutput("eprint", e.stderr, joinInspected(args))
+}
+
+func joinInspected(args []object.Object) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.Inspect()
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeOutput(name string, w io.Writer, s string) object.Object {
+	if _, err := io.WriteString(w, s); err != nil {
+		return newError("`%s` failed: %s", name, err)
+	}
+	return NULL
+}
@@ -0,0 +1,132 @@
+package evaluator
+
+import (
+	"regexp"
+
+	"github.com/yagihash/monkey/object"
+)
+
+const (
+	// maxPatternSize caps the length of patterns accepted from scripts.
+	maxPatternSize = 4096
+	// maxCachedPatterns bounds the number of compiled patterns kept around.
+	maxCachedPatterns = 256
+)
+
+func (e *Evaluator) compilePattern(name string, args []object.Object, pos int) (*regexp.Regexp, *object.Error) {
+	pattern, err := stringArg(name, args, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pattern) > maxPatternSize {
+		return nil, newError("pattern passed to `%s` too large: %d bytes, max %d", name, len(pattern), maxPatternSize)
+	}
+
+	if re, ok := e.patterns[pattern]; ok {
+		return re, nil
+	}
+
+	re, compileErr := regexp.Compile(pattern)
+	if compileErr != nil {
+		return nil, newError("invalid pattern passed to `%s`: %s", name, compileErr)
+	}
+
+	if len(e.patterns) >= maxCachedPatterns {
+		e.patterns = make(map[string]*regexp.Regexp)
+	}
+	e.patterns[pattern] = re
+
+	return re, nil
+}
+
+func (e *Evaluator) builtinReMatch(args ...object.Object) object.Object {
+	if err := checkArgs("re_match", args, 2); err != nil {
+		return err
+	}
+
+	re, err := e.compilePattern("re_match", args, 0)
+	if err != nil {
+		return err
+	}
+
+	s, err := stringArg("re_match", args, 1)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(re.MatchString(s))
+}
+
+func (e *Evaluator) builtinReFindAll(args ...object.Object) object.Object {
+	if err := checkArgsRange("re_find_all", args, 2, 3); err != nil {
+		return err
+	}
+
+	re, err := e.compilePattern("re_find_all", args, 0)
+	if err != nil {
+		return err
+	}
+
+	s, err := stringArg("re_find_all", args, 1)
+	if err != nil {
+		return err
+	}
+
+	n := int64(-1)
+	if len(args) == 3 {
+		if n, err = integerArg("re_find_all", args, 2); err != nil {
+			return err
+		}
+	}
+
+	return stringsToArray(re.FindAllString(s, int(n)))
+}
+
+func (e *Evaluator) builtinReReplace(args ...object.Object) object.Object {
+	if err := checkArgs("re_replace", args, 3); err != nil {
+		return err
+	}
+
+	re, err := e.compilePattern("re_replace", args, 0)
+	if err != nil {
+		return err
+	}
+
+	s, err := stringArg("re_replace", args, 1)
+	if err != nil {
+		return err
+	}
+
+	repl, err := stringArg("re_replace", args, 2)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: re.ReplaceAllString(s, repl)}
+}
+
+func (e *Evaluator) builtinReSplit(args ...object.Object) object.Object {
+	if err := checkArgsRange("re_split", args, 2, 3); err != nil {
+		return err
+	}
+
+	re, err := e.compilePattern("re_split", args, 0)
+	if err != nil {
+		return err
+	}
+
+	s, err := stringArg("re_split", args, 1)
+	if err != nil {
+		return err
+	}
+
+	n := int64(-1)
+	if len(args) == 3 {
+		if n, err = integerArg("re_split", args, 2); err != nil {
+			return err
+		}
+	}
+
+	return stringsToArray(re.Split(s, int(n)))
+}
@@ -0,0 +1,241 @@
+package evaluator
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yagihash/monkey/object"
+)
+
+const maxStringLength = 1 << 30
+
+func builtinSplit(args ...object.Object) object.Object {
+	if err := checkArgs("split", args, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg("split", args, 0)
+	if err != nil {
+		return err
+	}
+
+	sep, err := stringArg("split", args, 1)
+	if err != nil {
+		return err
+	}
+
+	return stringsToArray(strings.Split(s, sep))
+}
+
+func builtinJoin(args ...object.Object) object.Object {
+	if err := checkArgs("join", args, 2); err != nil {
+		return err
+	}
+
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return argTypeError("join", 0, object.ArrayObj, args[0])
+	}
+
+	sep, err := stringArg("join", args, 1)
+	if err != nil {
+		return err
+	}
+
+	parts := make([]string, len(array.Elements))
+	for i, el := range array.Elements {
+		s, ok := el.(*object.String)
+		if !ok {
+			return newError("element %d passed to `join` must be STRING, got %s", i, el.Type())
+		}
+		parts[i] = s.Value
+	}
+
+	return &object.String{Value: strings.Join(parts, sep)}
+}
+
+func builtinUpper(args ...object.Object) object.Object {
+	return mapString("upper", args, strings.ToUpper)
+}
+
+func builtinLower(args ...object.Object) object.Object {
+	return mapString("lower", args, strings.ToLower)
+}
+
+func mapString(name string, args []object.Object, fn func(string) string) object.Object {
+	if err := checkArgs(name, args, 1); err != nil {
+		return err
+	}
+
+	s, err := stringArg(name, args, 0)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: fn(s)}
+}
+
+func builtinTrim(args ...object.Object) object.Object {
+	if err := checkArgsRange("trim", args, 1, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg("trim", args, 0)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 {
+		return &object.String{Value: strings.TrimSpace(s)}
+	}
+
+	cutset, err := stringArg("trim", args, 1)
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: strings.Trim(s, cutset)}
+}
+
+func builtinReplace(args ...object.Object) object.Object {
+	if err := checkArgsRange("replace", args, 3, 4); err != nil {
+		return err
+	}
+
+	var strs [3]string
+	for i := range strs {
+		s, err := stringArg("replace", args, i)
+		if err != nil {
+			return err
+		}
+		strs[i] = s
+	}
+
+	n := int64(-1)
+	if len(args) == 4 {
+		var err *object.Error
+		if n, err = integerArg("replace", args, 3); err != nil {
+			return err
+		}
+	}
+
+	return &object.String{Value: strings.Replace(strs[0], strs[1], strs[2], int(n))}
+}
+
+func builtinContains(args ...object.Object) object.Object {
+	return testStrings("contains", args, strings.Contains)
+}
+
+func builtinStartsWith(args ...object.Object) object.Object {
+	return testStrings("starts_with", args, strings.HasPrefix)
+}
+
+func builtinEndsWith(args ...object.Object) object.Object {
+	return testStrings("ends_with", args, strings.HasSuffix)
+}
+
+func testStrings(name string, args []object.Object, fn func(string, string) bool) object.Object {
+	if err := checkArgs(name, args, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg(name, args, 0)
+	if err != nil {
+		return err
+	}
+
+	sub, err := stringArg(name, args, 1)
+	if err != nil {
+		return err
+	}
+
+	return nativeBoolToBooleanObject(fn(s, sub))
+}
+
+func builtinIndexOf(args ...object.Object) object.Object {
+	if err := checkArgs("index_of", args, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg("index_of", args, 0)
+	if err != nil {
+		return err
+	}
+
+	sub, err := stringArg("index_of", args, 1)
+	if err != nil {
+		return err
+	}
+
+	i := strings.Index(s, sub)
+	if i < 0 {
+		return newInteger(-1)
+	}
+
+	return newInteger(int64(utf8.RuneCountInString(s[:i])))
+}
+
+func builtinRepeat(args ...object.Object) object.Object {
+	if err := checkArgs("repeat", args, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg("repeat", args, 0)
+	if err != nil {
+		return err
+	}
+
+	n, err := integerArg("repeat", args, 1)
+	if err != nil {
+		return err
+	}
+
+	if n < 0 {
+		return newError("negative count passed to `repeat`: %d", n)
+	}
+
+	if len(s) > 0 && n > int64(maxStringLength/len(s)) {
+		return newError("result of `repeat` too large: %d * %d bytes", n, len(s))
+	}
+
+	return &object.String{Value: strings.Repeat(s, int(n))}
+}
+
+func builtinSubstr(args ...object.Object) object.Object {
+	if err := checkArgsRange("substr", args, 2, 3); err != nil {
+		return err
+	}
+
+	s, err := stringArg("substr", args, 0)
+	if err != nil {
+		return err
+	}
+
+	start, err := integerArg("substr", args, 1)
+	if err != nil {
+		return err
+	}
+
+	runes := []rune(s)
+	if start < 0 || start > int64(len(runes)) {
+		return newError("start index passed to `substr` out of range: %d", start)
+	}
+
+	end := int64(len(runes))
+	if len(args) == 3 {
+		length, err := integerArg("substr", args, 2)
+		if err != nil {
+			return err
+		}
+
+		if length < 0 {
+			return newError("negative length passed to `substr`: %d", length)
+		}
+
+		if length < end-start {
+			end = start + length
+		}
+	}
+
+	return &object.String{Value: string(runes[start:end])}
+}
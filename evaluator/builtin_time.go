@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"math"
+	"time"
+
+	"github.com/yagihash/monkey/object"
+)
+
+// Times are represented as milliseconds since the Unix epoch and durations as
+// milliseconds, so that both can be combined with ordinary integer arithmetic.
+
+// maxDuration is the longest duration in milliseconds a time.Duration holds.
+const maxDuration = int64(math.MaxInt64 / time.Millisecond)
+
+func (e *Evaluator) builtinNow(args ...object.Object) object.Object {
+	if err := checkArgs("now", args, 0); err != nil {
+		return err
+	}
+
+	return &object.Integer{Value: toMillis(e.clock.Now())}
+}
+
+func (e *Evaluator) builtinSleep(args ...object.Object) object.Object {
+	if err := checkArgs("sleep", args, 1); err != nil {
+		return err
+	}
+
+	ms, err := integerArg("sleep", args, 0)
+	if err != nil {
+		return err
+	}
+
+	if ms < 0 {
+		return newError("negative duration passed to `sleep`: %d", ms)
+	}
+	if ms > maxDuration {
+		return newError("duration passed to `sleep` out of range: %d", ms)
+	}
+
+	e.clock.Sleep(time.Duration(ms) * time.Millisecond)
+
+	return NULL
+}
+
+func builtinDuration(args ...object.Object) object.Object {
+	if err := checkArgs("duration", args, 1); err != nil {
+		return err
+	}
+
+	s, err := stringArg("duration", args, 0)
+	if err != nil {
+		return err
+	}
+
+	d, parseErr := time.ParseDuration(s)
+	if parseErr != nil {
+		return newError("invalid duration passed to `duration`: %q", s)
+	}
+
+	return &object.Integer{Value: d.Milliseconds()}
+}
+
+func builtinFormatTime(args ...object.Object) object.Object {
+	if err := checkArgsRange("format_time", args, 1, 3); err != nil {
+		return err
+	}
+
+	ms, err := integerArg("format_time", args, 0)
+	if err != nil {
+		return err
+	}
+
+	layout := time.RFC3339
+	if len(args) >= 2 {
+		if layout, err = stringArg("format_time", args, 1); err != nil {
+			return err
+		}
+	}
+
+	loc := time.UTC
+	if len(args) == 3 {
+		name, err := stringArg("format_time", args, 2)
+		if err != nil {
+			return err
+		}
+
+		var loadErr error
+		if loc, loadErr = time.LoadLocation(name); loadErr != nil {
+			return newError("unknown time zone passed to `format_time`: %q", name)
+		}
+	}
+
+	return &object.String{Value: fromMillis(ms).In(loc).Format(layout)}
+}
+
+func builtinParseTime(args ...object.Object) object.Object {
+	if err := checkArgsRange("parse_time", args, 1, 2); err != nil {
+		return err
+	}
+
+	s, err := stringArg("parse_time", args, 0)
+	if err != nil {
+		return err
+	}
+
+	layout := time.RFC3339
+	if len(args) == 2 {
+		if layout, err = stringArg("parse_time", args, 1); err != nil {
+			return err
+		}
+	}
+
+	t, parseErr := time.Parse(layout, s)
+	if parseErr != nil {
+		return newError("invalid time passed to `parse_time`: %s", parseErr)
+	}
+
+	return &object.Integer{Value: toMillis(t)}
+}
+
+func toMillis(t time.Time) int64 {
+	return t.Unix()*1000 + int64(t.Nanosecond())/int64(time.Millisecond)
+}
+
+func fromMillis(ms int64) time.Time {
+	return time.Unix(ms/1000, ms%1000*int64(time.Millisecond)).UTC()
+}
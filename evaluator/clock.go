@@ -0,0 +1,27 @@
+package evaluator
+
+import "time"
+
+// Clock is the source of time for the time builtins and select. Hosts can
+// supply their own implementation with WithClock, e.g. to replay a script
+// deterministically.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	// After returns a channel that receives the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
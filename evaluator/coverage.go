@@ -0,0 +1,19 @@
+package evaluator
+
+import "github.com/yagihash/monkey/ast"
+
+// Coverage records which statements and branches of a program an Evaluator
+// runs. Like Hook, its methods are called on the goroutine doing the
+// evaluation, but they are also called on the goroutines of the functions
+// spawned by the program, so they must be safe for concurrent use.
+type Coverage interface {
+	// Module is called with the program of each module, read from path in the
+	// file system of the ModuleLoader, before it is evaluated.
+	Module(path string, program *ast.Program)
+	// Statement is called before stmt is evaluated.
+	Statement(stmt ast.Statement)
+	// Branch is called once the condition of ie has been evaluated, telling
+	// whether the consequence or the alternative runs. An alternative is
+	// taken even when ie has none.
+	Branch(ie *ast.IfExpression, consequence bool)
+}
@@ -0,0 +1,118 @@
+package evaluator
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+func TestFrozenEnvironment(t *testing.T) {
+	cases := []struct {
+		globals string
+		input   string
+		want    string
+	}{
+		{"let x = 1;", "x + 1", "2"},
+		{"let x = 1;", "let x = 2; x", "2"},
+		{"let x = 1;", "let f = fn() { let x = 3; x }; [f(), x]", "[3, 1]"},
+		{"let adder = fn(n) { fn(x) { x + n } }; let addTwo = adder(2);", "addTwo(3)", "5"},
+		{"let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } };", "fib(10)", "55"},
+		{"let config = {\"retries\": 3};", "config[\"retries\"] * 2", "6"},
+		{"let square = fn(x) { x * x };", "recv(spawn(square, 4))", "16"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			globals := testFrozenGlobals(t, c.globals)
+			names := globals.Names()
+
+			env := object.NewEnclosedEnvironment(globals)
+			if got := testEvalIn(t, c.input, env).Inspect(); got != c.want {
+				t.Errorf("unexpected result. want=%q, got=%q", c.want, got)
+			}
+
+			if diff := cmp.Diff(names, globals.Names()); diff != "" {
+				t.Errorf("frozen environment changed. (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFrozenEnvironmentBinding(t *testing.T) {
+	globals := testFrozenGlobals(t, "let x = 1;")
+
+	want := "ERROR: cannot bind in a frozen environment: y"
+	if got := testEvalIn(t, "let y = 2; y", globals).Inspect(); got != want {
+		t.Errorf("unexpected result. want=%q, got=%q", want, got)
+	}
+	if _, ok := globals.Get("y"); ok {
+		t.Errorf("y bound in frozen environment")
+	}
+}
+
+// TestFrozenEnvironmentRace evaluates scripts concurrently against a shared
+// frozen environment, each with an Evaluator and an environment of its own.
+// Run it with -race.
+func TestFrozenEnvironmentRace(t *testing.T) {
+	globals := testFrozenGlobals(t, `
+let base = 10;
+let adder = fn(n) { fn(x) { x + n + base } };
+let addOne = adder(1);
+let weights = {"a": 1, "b": 2};
+let sum = fn(xs, i, acc) { if (i == len(xs)) { acc } else { sum(xs, i + 1, acc + xs[i]) } };
+`)
+
+	const workers = 16
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			input := fmt.Sprintf(`
+let n = %d;
+let base = n;
+let f = fn(x) { addOne(x) + base };
+let r = spawn(fn() { sum([n, weights["b"], addOne(0)], 0, 0) });
+f(n) + recv(r)
+`, i)
+			env := object.NewEnclosedEnvironment(globals)
+			program := parser.New(lexer.New(input)).ParseProgram()
+
+			// addOne(n) + n is n + 1 + 10 + n, and the sum n + 2 + 11.
+			want := fmt.Sprint(3*i + 24)
+			if got := New().Eval(program, env).Inspect(); got != want {
+				t.Errorf("unexpected result of worker %d. want=%q, got=%q", i, want, got)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func testFrozenGlobals(t *testing.T, input string) *object.Environment {
+	t.Helper()
+
+	env := object.NewEnvironment()
+	if result := testEvalIn(t, input, env); isError(result) {
+		t.Fatalf("unexpected error evaluating globals: %s", result.Inspect())
+	}
+	env.Freeze()
+
+	return env
+}
+
+func testEvalIn(t *testing.T, input string, env *object.Environment) object.Object {
+	t.Helper()
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	result := New().Eval(program, env)
+	if result == nil {
+		return NULL
+	}
+	return result
+}
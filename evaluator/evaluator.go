@@ -2,6 +2,11 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"sync"
 
 	"github.com/yagihash/monkey/ast"
 	"github.com/yagihash/monkey/object"
@@ -13,85 +18,254 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
+// Evaluator holds the state shared by every evaluation it performs, such as
+// the module cache. It is not safe for concurrent use: spawned functions run
+// on Evaluators of their own, see fork.
+type Evaluator struct {
+	loader   *ModuleLoader
+	builtins map[string]*object.Builtin
+	patterns map[string]*regexp.Regexp
+	clock    Clock
+	fsys     fs.FS
+	wfs      WriteFS
+	stdout   io.Writer
+	stderr   io.Writer
+	hook     Hook
+	coverage Coverage
+
+	// call is the call expression whose function is being applied, telling
+	// the assertion builtins where they were called.
+	call *ast.CallExpression
+
+	// allocs counts the values allocated by the evaluation, see Allocations.
+	allocs uint64
+
+	// tail is the tailCall returned by the calls in tail position. The
+	// trampoline reads it before anything else is evaluated, so one is
+	// enough.
+	tail tailCall
+
+	// free holds the environments released by calls that have returned, see
+	// release.
+	free []*object.Environment
+
+	// stack holds the arguments of the calls being made without a Hook,
+	// see evalCall.
+	stack []object.Object
+
+	// strings holds the values of the string literals evaluated so far, and
+	// programs counts the programs being evaluated, see endProgram.
+	strings  map[*ast.StringLiteral]*object.String
+	programs int
+
+	// importing counts the modules being loaded by e, see ModuleLoader.
+	importing int
+
+	// output serializes the writes to stdout and stderr once functions have
+	// been spawned, see fork.
+	output *sync.Mutex
+}
+
+func New(opts ...Option) *Evaluator {
+	e := &Evaluator{
+		clock:  systemClock{},
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	e.init()
+
+	return e
+}
+
+// init creates the state of e that options do not configure.
+func (e *Evaluator) init() {
+	e.patterns = make(map[string]*regexp.Regexp)
+	e.strings = make(map[*ast.StringLiteral]*object.String)
+
+	bound := e.boundBuiltins()
+	e.builtins = make(map[string]*object.Builtin, len(builtins)+len(bound))
+	for name, builtin := range builtins {
+		e.builtins[name] = builtin
+	}
+	for name, builtin := range bound {
+		e.builtins[name] = builtin
+	}
+}
+
+// Eval evaluates node with a fresh Evaluator using the default options.
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+func (e *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return e.Eval(node.Expression, env)
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return e.allocated(newInteger(node.Value))
+	case *ast.FloatLiteral:
+		return e.allocated(&object.Float{Value: node.Value})
 	case *ast.StringLiteral:
-		return &object.String{Value: node.Value}
+		return e.evalStringLiteral(node)
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return e.allocated(evalPrefixExpression(node.Operator, right))
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		return e.allocated(evalInfixExpression(node.Operator, left, right))
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := e.Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := e.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if env.Frozen() {
+			return newError("cannot bind in a frozen environment: %s", node.Name.Value)
+		}
+		if node.Name.Scope == ast.ScopeLocal {
+			env.SetSlot(node.Name.Slot, val)
+		} else {
+			env.Set(node.Name.Value, val)
+		}
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return e.evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{
+		return e.allocated(&object.Function{
 			Parameters: params,
 			Body:       body,
 			Env:        env,
+			Locals:     node.Locals,
+			Leaf:       node.Leaf,
+		})
+	case *ast.ArrayLiteral:
+		elements := e.evalExpressions(node.Elements, env)
+		if len(elements) == 1 && isError(elements[0]) {
+			return elements[0]
+		}
+		return e.allocated(&object.Array{Elements: elements})
+	case *ast.HashLiteral:
+		return e.evalHashLiteral(node, env)
+	case *ast.IndexExpression:
+		left := e.Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := e.Eval(node.Index, env)
+		if isError(index) {
+			return index
 		}
+		return evalIndexExpression(left, index)
+	case *ast.ImportExpression:
+		if e.loader == nil {
+			return newError("cannot import %s: no module loader", node.Path.Value)
+		}
+		return e.loader.load(e, node.Path.Value)
+	case *ast.MemberExpression:
+		obj := e.Eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Property.Value)
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := e.Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		if e.hook == nil {
+			return e.evalCall(node, function, env)
+		}
+		args := e.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return callFunction(function, args)
+		e.call = node
+		if _, ok := function.(*object.Function); ok && node.Tail {
+			e.tail = tailCall{call: node, fn: function, args: args}
+			return &e.tail
+		}
+		return e.callHooked(node, function, args)
 	}
 
 	return nil
 }
 
-func callFunction(fn object.Object, args []object.Object) object.Object {
+// callFunction applies fn to args. It is a trampoline: the calls in tail
+// position of function bodies return a tailCall instead of nesting another
+// callFunction, and it makes them in turn, so that tail recursion runs in
+// constant Go stack space.
+func (e *Evaluator) callFunction(fn object.Object, args []object.Object) object.Object {
+	for {
+		result := e.applyFunction(fn, args)
+
+		tc, ok := result.(*tailCall)
+		if !ok {
+			return result
+		}
+		fn, args = tc.fn, tc.args
+	}
+}
+
+// callHooked is callFunction reporting the calls it makes to the Hook. A call
+// in tail position replaces the frame of the function making it, whose Return
+// is reported before the Call.
+func (e *Evaluator) callHooked(call *ast.CallExpression, fn object.Object, args []object.Object) object.Object {
+	e.hook.Call(call, fn, args)
+	for {
+		result := e.applyFunction(fn, args)
+
+		tc, ok := result.(*tailCall)
+		if !ok {
+			e.hook.Return(call, fn, result)
+			return result
+		}
+
+		e.hook.Return(call, fn, nil)
+		call, fn, args = tc.call, tc.fn, tc.args
+		e.hook.Call(call, fn, args)
+	}
+}
+
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
 	switch fn := fn.(type) {
 
 	case *object.Function:
-		extendedEnv := enclosedFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
+		extendedEnv := e.functionEnv(fn, args)
+		evaluated := e.Eval(fn.Body, extendedEnv)
+		e.release(extendedEnv, fn)
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return e.allocated(fn.Fn(args...), args...)
 
 	default:
 		return newError("not a function: %s", fn.Type())
@@ -99,29 +273,44 @@ func callFunction(fn object.Object, args []object.Object) object.Object {
 
 }
 
-func unwrapReturnValue(obj object.Object) object.Object {
-	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+// Allocations returns the number of values allocated by the evaluations so
+// far: literals, the results of operators and builtins, and the environments
+// of function calls. Values that are reused, such as small integers, string
+// literals evaluated before, environments released by earlier calls and
+// results shared with their operands, are not counted.
+func (e *Evaluator) Allocations() uint64 {
+	return e.allocs
+}
+
+// allocated counts obj as allocated, unless it is nil, shared or one of the
+// operands it was computed from.
+func (e *Evaluator) allocated(obj object.Object, operands ...object.Object) object.Object {
+	if obj == nil || isShared(obj) {
+		return obj
+	}
+	for _, operand := range operands {
+		if obj == operand {
+			return obj
+		}
 	}
 
+	e.allocs++
 	return obj
 }
 
-func enclosedFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
-
-	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+func unwrapReturnValue(obj object.Object) object.Object {
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
 	}
 
-	return env
+	return obj
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
-	var result []object.Object
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	result := make([]object.Object, 0, len(exps))
 
-	for _, e := range exps {
-		evaluated := Eval(e, env)
+	for _, exp := range exps {
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -131,28 +320,132 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	if val, ok := env.Get(node.Value); ok {
-		return val
+func (e *Evaluator) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	switch node.Scope {
+	case ast.ScopeLocal:
+		scope := outerEnv(env, node.Depth)
+		if val := scope.Slot(node.Slot); val != nil {
+			return val
+		}
+		// The let statement binding the slot has not run yet, so the name
+		// may still be bound further out.
+		if val, ok := scope.Get(node.Value); ok {
+			return val
+		}
+	case ast.ScopeGlobal:
+		if val, ok := outerEnv(env, node.Depth).Get(node.Value); ok {
+			return val
+		}
+	default:
+		if val, ok := env.Get(node.Value); ok {
+			return val
+		}
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := e.builtins[node.Value]; ok {
 		return builtin
 	}
 
+	if constant, ok := constants[node.Value]; ok {
+		return constant
+	}
+
 	return newError("identifier not found: " + node.Value)
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ie.Condition, env)
+// outerEnv returns the environment depth functions out of env.
+func outerEnv(env *object.Environment, depth int) *object.Environment {
+	for ; depth > 0; depth-- {
+		env = env.Outer()
+	}
+	return env
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ArrayObj && index.Type() == object.IntegerObj:
+		elements := left.(*object.Array).Elements
+		idx := index.(*object.Integer).Value
+		if idx < 0 || idx >= int64(len(elements)) {
+			return NULL
+		}
+		return elements[idx]
+	case left.Type() == object.HashObj:
+		return evalHashIndexExpression(left, index)
+	default:
+		return newError("index operator not supported: %s", left.Type())
+	}
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	pair, ok := hashObject.Pairs[key.HashKey()]
+	if !ok {
+		return NULL
+	}
+
+	return pair.Value
+}
+
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair, len(node.Pairs))
+
+	for _, pair := range node.Pairs {
+		key := e.Eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := e.Eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return e.allocated(&object.Hash{Pairs: pairs})
+}
+
+func evalMemberExpression(obj object.Object, name string) object.Object {
+	module, ok := obj.(*object.Module)
+	if !ok {
+		return newError("member access not supported: %s", obj.Type())
+	}
+
+	if val, ok := module.Exports[name]; ok {
+		return val
+	}
+
+	return newError("member not found: %s in %s", name, module.Path)
+}
+
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
+	condition := e.Eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 
-	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+	truthy := isTruthy(condition)
+	if e.coverage != nil {
+		e.coverage.Branch(ie, truthy)
+	}
+
+	if truthy {
+		return e.Eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return e.Eval(ie.Alternative, env)
 	} else {
 		return NULL
 	}
@@ -175,6 +468,8 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	switch {
 	case left.Type() == object.IntegerObj && right.Type() == object.IntegerObj:
 		return evalIntegerInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.StringObj && right.Type() == object.StringObj:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
@@ -212,13 +507,16 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		return newInteger(leftVal + rightVal)
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		return newInteger(leftVal - rightVal)
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		return newInteger(leftVal * rightVal)
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+		return newInteger(leftVal / rightVal)
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -232,6 +530,47 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.IntegerObj || obj.Type() == object.FloatObj
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
@@ -244,12 +583,14 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.IntegerObj {
+	switch right := right.(type) {
+	case *object.Integer:
+		return newInteger(-right.Value)
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
-
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
 }
 
 func evalNotOperatorExpression(right object.Object) object.Object {
@@ -265,11 +606,20 @@ func evalNotOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	e.programs++
+	defer e.endProgram()
+
 	var result object.Object
 
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		if e.hook != nil {
+			e.hook.Statement(statement, env)
+		}
+		if e.coverage != nil {
+			e.coverage.Statement(statement)
+		}
+		result = e.Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -282,11 +632,17 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		if e.hook != nil {
+			e.hook.Statement(statement, env)
+		}
+		if e.coverage != nil {
+			e.coverage.Statement(statement)
+		}
+		result = e.Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -1,8 +1,17 @@
 package evaluator
 
 import (
+	"io/fs"
+	"math"
+	"runtime/debug"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/ast"
 	"github.com/yagihash/monkey/lexer"
 	"github.com/yagihash/monkey/parser"
 
@@ -188,6 +197,10 @@ f(10);`,
 				"-true",
 				"unknown operator: -BOOLEAN",
 			},
+			{
+				"10 / (5 - 5)",
+				"division by zero",
+			},
 			{
 				"true + false;",
 				"unknown operator: BOOLEAN + BOOLEAN",
@@ -366,8 +379,10 @@ if (10 > 1) {
 			{`len("")`, 0},
 			{`len("four")`, 4},
 			{`len("hello world")`, 11},
+			{`len("日本語")`, 3},
+			{`len([1, 2, 3])`, 3},
 			{`len(1)`, "argument to `len` not supported, got INTEGER"},
-			{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
+			{`len("one", "two")`, "wrong number of arguments to `len`. got=2, want=1"},
 		}
 
 		for _, c := range cases {
@@ -391,6 +406,794 @@ if (10 > 1) {
 			})
 		}
 	})
+
+	t.Run("ArrayLiterals", func(t *testing.T) {
+		evaluated := testEval(t, "[1, 2 * 2, 3 + 3]")
+		result, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+		}
+
+		if len(result.Elements) != 3 {
+			t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+		}
+
+		testIntegerObject(t, result.Elements[0], 1)
+		testIntegerObject(t, result.Elements[1], 4)
+		testIntegerObject(t, result.Elements[2], 6)
+	})
+
+	t.Run("ArrayIndexExpressions", func(t *testing.T) {
+		cases := []struct {
+			input    string
+			expected interface{}
+		}{
+			{"[1, 2, 3][0]", 1},
+			{"[1, 2, 3][2]", 3},
+			{"let i = 0; [1][i];", 1},
+			{"[1, 2, 3][1 + 1];", 3},
+			{"let myArray = [1, 2, 3]; myArray[0] + myArray[1] + myArray[2];", 6},
+			{"[1, 2, 3][3]", nil},
+			{"[1, 2, 3][-1]", nil},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				evaluated := testEval(t, c.input)
+				integer, ok := c.expected.(int)
+				if ok {
+					testIntegerObject(t, evaluated, int64(integer))
+				} else {
+					testNullObject(t, evaluated)
+				}
+			})
+		}
+	})
+
+	t.Run("StringBuiltins", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  interface{}
+		}{
+			{`split("a,b,c", ",")`, []string{"a", "b", "c"}},
+			{`split("日本", "")`, []string{"日", "本"}},
+			{`join(["a", "b", "c"], "-")`, "a-b-c"},
+			{`join(split("a b", " "), ",")`, "a,b"},
+			{`upper("monkey")`, "MONKEY"},
+			{`lower("MoNKey")`, "monkey"},
+			{`trim("  monkey  ")`, "monkey"},
+			{`trim("xxmonkeyx", "x")`, "monkey"},
+			{`replace("aaa", "a", "b")`, "bbb"},
+			{`replace("aaa", "a", "b", 2)`, "bba"},
+			{`contains("monkey", "key")`, true},
+			{`contains("monkey", "ape")`, false},
+			{`starts_with("monkey", "mon")`, true},
+			{`ends_with("monkey", "mon")`, false},
+			{`index_of("日本語", "語")`, 2},
+			{`index_of("monkey", "ape")`, -1},
+			{`repeat("ab", 3)`, "ababab"},
+			{`substr("日本語です", 1, 2)`, "本語"},
+			{`substr("monkey", 3)`, "key"},
+			{`substr("monkey", 3, 100)`, "key"},
+			{`substr("abc", 1, 9223372036854775807)`, "bc"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				evaluated := testEval(t, c.input)
+
+				switch want := c.want.(type) {
+				case int:
+					testIntegerObject(t, evaluated, int64(want))
+				case bool:
+					testBooleanObject(t, evaluated, want)
+				case string:
+					testStringObject(t, evaluated, want)
+				case []string:
+					array, ok := evaluated.(*object.Array)
+					if !ok {
+						t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+					}
+					if len(array.Elements) != len(want) {
+						t.Fatalf("array has wrong num of elements. got=%d, want=%d", len(array.Elements), len(want))
+					}
+					for i, w := range want {
+						testStringObject(t, array.Elements[i], w)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("BuiltinArgumentErrors", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  string
+		}{
+			{`split("a")`, "wrong number of arguments to `split`. got=1, want=2"},
+			{`split(1, ",")`, "argument 1 to `split` must be STRING, got INTEGER"},
+			{`join("a", ",")`, "argument 1 to `join` must be ARRAY, got STRING"},
+			{`join(["a", 1], ",")`, "element 1 passed to `join` must be STRING, got INTEGER"},
+			{`trim()`, "wrong number of arguments to `trim`. got=0, want=1..2"},
+			{`replace("a", "b", 1)`, "argument 3 to `replace` must be STRING, got INTEGER"},
+			{`contains("a", true)`, "argument 2 to `contains` must be STRING, got BOOLEAN"},
+			{`repeat("a", -1)`, "negative count passed to `repeat`: -1"},
+			{`substr("abc", 4)`, "start index passed to `substr` out of range: 4"},
+			{`substr("abc", 0, -1)`, "negative length passed to `substr`: -1"},
+			{`upper("a", "b")`, "wrong number of arguments to `upper`. got=2, want=1"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				testErrorObject(t, testEval(t, c.input), c.want)
+			})
+		}
+	})
+
+	t.Run("FloatExpression", func(t *testing.T) {
+		cases := []struct {
+			input    string
+			expected interface{}
+		}{
+			{"2.5", 2.5},
+			{"-2.5", -2.5},
+			{"1.5 + 1", 2.5},
+			{"1 - 0.5", 0.5},
+			{"2 * 1.25", 2.5},
+			{"5 / 2.0", 2.5},
+			{"1.5 < 2", true},
+			{"2 == 2.0", true},
+			{"0.1 + 0.2 != 0.3", true},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				evaluated := testEval(t, c.input)
+				switch expected := c.expected.(type) {
+				case float64:
+					testFloatObject(t, evaluated, expected)
+				case bool:
+					testBooleanObject(t, evaluated, expected)
+				}
+			})
+		}
+	})
+
+	t.Run("MathBuiltins", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  interface{}
+		}{
+			{`abs(-5)`, 5},
+			{`abs(-2.5)`, 2.5},
+			{`min(3, 1, 2)`, 1},
+			{`min(3, 1.5)`, 1.5},
+			{`max(3, 7, 2)`, 7},
+			{`pow(2, 10)`, 1024},
+			{`pow(2, 62)`, int64(4611686018427387904)},
+			{`pow(-2, 63)`, int64(-9223372036854775808)},
+			{`abs(-9223372036854775807)`, int64(9223372036854775807)},
+			{`gcd(-9223372036854775807 - 1, 6)`, 2},
+			{`pow(2, -1)`, 0.5},
+			{`pow(4.0, 0.5)`, 2.0},
+			{`sqrt(16)`, 4.0},
+			{`gcd(12, 18)`, 6},
+			{`gcd(-12, 18)`, 6},
+			{`clamp(15, 0, 10)`, 10},
+			{`clamp(-1, 0, 10)`, 0},
+			{`clamp(5, 0, 10)`, 5},
+			{`floor(2.7)`, 2},
+			{`floor(-2.5)`, -3},
+			{`ceil(2.1)`, 3},
+			{`round(2.5)`, 3},
+			{`round(7)`, 7},
+			{`PI`, math.Pi},
+			{`E`, math.E},
+			{`round(PI * 100)`, 314},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				evaluated := testEval(t, c.input)
+				switch want := c.want.(type) {
+				case int:
+					testIntegerObject(t, evaluated, int64(want))
+				case int64:
+					testIntegerObject(t, evaluated, want)
+				case float64:
+					testFloatObject(t, evaluated, want)
+				}
+			})
+		}
+	})
+
+	t.Run("MathBuiltinErrors", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  string
+		}{
+			{`abs("a")`, "argument 1 to `abs` must be INTEGER or FLOAT, got STRING"},
+			{`min()`, "wrong number of arguments to `min`. got=0, want=1 or more"},
+			{`max(1, true)`, "argument 2 to `max` must be INTEGER or FLOAT, got BOOLEAN"},
+			{`pow(2)`, "wrong number of arguments to `pow`. got=1, want=2"},
+			{`pow(2, 64)`, "result of `pow` out of integer range: 2^64"},
+			{`pow(-3, 41)`, "result of `pow` out of integer range: -3^41"},
+			{`sqrt(-4)`, "negative argument passed to `sqrt`: -4"},
+			{`gcd(1.5, 2)`, "argument 1 to `gcd` must be INTEGER, got FLOAT"},
+			{`abs(-9223372036854775807 - 1)`, "result of `abs` out of integer range: -9223372036854775808"},
+			{`gcd(-9223372036854775807 - 1, 0)`, "result of `gcd` out of integer range: gcd(-9223372036854775808, 0)"},
+			{`gcd(0, -9223372036854775807 - 1)`, "result of `gcd` out of integer range: gcd(0, -9223372036854775808)"},
+			{`clamp(1, 10, 0)`, "lower bound passed to `clamp` is greater than upper bound: 10 > 0"},
+			{`floor(sqrt(-0.0) / 0.0)`, "argument to `floor` out of integer range: NaN"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				testErrorObject(t, testEval(t, c.input), c.want)
+			})
+		}
+	})
+
+	t.Run("HashLiterals", func(t *testing.T) {
+		input := `let two = "two";
+{
+  "one": 10 - 9,
+  two: 1 + 1,
+  "thr" + "ee": 6 / 2,
+  4: 4,
+  true: 5,
+  false: 6
+}`
+
+		evaluated := testEval(t, input)
+		result, ok := evaluated.(*object.Hash)
+		if !ok {
+			t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+		}
+
+		expected := map[object.HashKey]int64{
+			(&object.String{Value: "one"}).HashKey():   1,
+			(&object.String{Value: "two"}).HashKey():   2,
+			(&object.String{Value: "three"}).HashKey(): 3,
+			(&object.Integer{Value: 4}).HashKey():      4,
+			TRUE.HashKey():                             5,
+			FALSE.HashKey():                            6,
+		}
+
+		if len(result.Pairs) != len(expected) {
+			t.Fatalf("Hash has wrong num of pairs. got=%d", len(result.Pairs))
+		}
+
+		for expectedKey, expectedValue := range expected {
+			pair, ok := result.Pairs[expectedKey]
+			if !ok {
+				t.Errorf("no pair for given key in Pairs")
+			}
+
+			testIntegerObject(t, pair.Value, expectedValue)
+		}
+	})
+
+	t.Run("HashIndexExpressions", func(t *testing.T) {
+		cases := []struct {
+			input    string
+			expected interface{}
+		}{
+			{`{"foo": 5}["foo"]`, 5},
+			{`{"foo": 5}["bar"]`, nil},
+			{`let key = "foo"; {"foo": 5}[key]`, 5},
+			{`{}["foo"]`, nil},
+			{`{5: 5}[5]`, 5},
+			{`{true: 5}[true]`, 5},
+			{`len({"a": 1, "b": 2})`, 2},
+			{`{"name": "Monkey"}[fn(x) { x }];`, "unusable as hash key: FUNCTION"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				evaluated := testEval(t, c.input)
+				switch expected := c.expected.(type) {
+				case int:
+					testIntegerObject(t, evaluated, int64(expected))
+				case string:
+					testErrorObject(t, evaluated, expected)
+				default:
+					testNullObject(t, evaluated)
+				}
+			})
+		}
+	})
+
+	t.Run("JSONBuiltins", func(t *testing.T) {
+		cases := []struct {
+			input   string
+			payload string
+			want    string
+		}{
+			{`json_stringify(json_parse(payload))`, `{"a": [1, 2.5, true, null, "x"]}`, `{"a":[1,2.5,true,null,"x"]}`},
+			{`json_stringify({"b": 1, "a": [1.0, payload]})`, "<&>", `{"a":[1.0,"<&>"],"b":1}`},
+			{`json_stringify(json_parse(payload), 2)`, `[1, {"k": null}]`, "[\n  1,\n  {\n    \"k\": null\n  }\n]"},
+			{`json_stringify([1], payload)`, "\t", "[\n\t1\n]"},
+			{`json_parse("[1, 2, 3]")[1]`, "", "2"},
+			{`json_parse(payload)["n"]`, `{"n": 1e3}`, "1000.0"},
+			{`json_parse(payload)`, `"text"`, "text"},
+			{`json_parse("[1, 2")`, "", "ERROR: invalid JSON passed to `json_parse` at offset 5: unexpected end of input"},
+			{`json_parse(payload)`, `{"a" 1}`, "ERROR: invalid JSON passed to `json_parse` at offset 5: invalid character '1' after object key"},
+			{`json_parse("[] []")`, "", "ERROR: invalid JSON passed to `json_parse` at offset 2: unexpected data after top-level value"},
+			{`json_stringify(fn(x) { x })`, "", "ERROR: value of type FUNCTION cannot be encoded by `json_stringify`"},
+			{`json_stringify([len])`, "", "ERROR: value of type BUILTIN cannot be encoded by `json_stringify`"},
+			{`json_stringify({1: 2})`, "", "ERROR: hash key of type INTEGER cannot be encoded by `json_stringify`"},
+			{`json_stringify(sqrt(-0.0) / 0.0)`, "", "ERROR: value NaN cannot be encoded by `json_stringify`"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				program := parser.New(lexer.New(c.input)).ParseProgram()
+				env := object.NewEnvironment()
+				env.Set("payload", &object.String{Value: c.payload})
+
+				evaluated := Eval(program, env)
+				if evaluated.Inspect() != c.want {
+					t.Errorf("unexpected result. want=%q, got=%q", c.want, evaluated.Inspect())
+				}
+			})
+		}
+	})
+
+	t.Run("RegexpBuiltins", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  string
+		}{
+			{`re_match("^ERROR \d+", "ERROR 42: disk full")`, "true"},
+			{`re_match("^ERROR", "WARN 1")`, "false"},
+			{`re_find_all("\d+", "a1b22c333")`, "[1, 22, 333]"},
+			{`re_find_all("\d+", "a1b22c333", 2)`, "[1, 22]"},
+			{`re_find_all("x", "abc")`, "[]"},
+			{`re_replace("(\w+)@(\w+)", "user@host", "${2}:${1}")`, "host:user"},
+			{`re_split("\s*,\s*", "a , b,c")`, "[a, b, c]"},
+			{`re_split(",", "a,b,c", 2)`, "[a, b,c]"},
+			{`re_match("(", "x")`, "ERROR: invalid pattern passed to `re_match`: error parsing regexp: missing closing ): `(`"},
+			{`re_match(repeat("a", 5000), "a")`, "ERROR: pattern passed to `re_match` too large: 5000 bytes, max 4096"},
+			{`re_replace("a", "b")`, "ERROR: wrong number of arguments to `re_replace`. got=2, want=3"},
+			{`re_split(1, "a")`, "ERROR: argument 1 to `re_split` must be STRING, got INTEGER"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				evaluated := testEval(t, c.input)
+				if evaluated.Inspect() != c.want {
+					t.Errorf("unexpected result. want=%q, got=%q", c.want, evaluated.Inspect())
+				}
+			})
+		}
+
+		t.Run("Cache", func(t *testing.T) {
+			e := New()
+			testEvalWith(t, e, `re_match("a+", "aaa"); re_match("a+", "b"); re_split("b", "abc")`)
+
+			if len(e.patterns) != 2 {
+				t.Errorf("unexpected number of cached patterns. want=2, got=%d", len(e.patterns))
+			}
+
+			if len(New().patterns) != 0 {
+				t.Errorf("patterns are shared between evaluators")
+			}
+		})
+	})
+
+	t.Run("TimeBuiltins", func(t *testing.T) {
+		start := time.Date(2020, 4, 1, 12, 0, 0, 0, time.UTC)
+
+		cases := []struct {
+			input string
+			want  string
+		}{
+			{`now()`, "1585742400000"},
+			{`let t = now(); sleep(1500); now() - t`, "1500"},
+			{`sleep(duration("1h")); format_time(now())`, "2020-04-01T13:00:00Z"},
+			{`duration("1m30s")`, "90000"},
+			{`format_time(0, "2006-01-02 15:04")`, "1970-01-01 00:00"},
+			{`format_time(0, "15:04 MST", "Asia/Tokyo")`, "09:00 JST"},
+			{`parse_time("2020-04-01T12:00:00Z") == now()`, "true"},
+			{`parse_time("01/04/2020", "02/01/2006")`, "1585699200000"},
+			{`format_time(parse_time("1500-01-01T00:00:00.250Z"), "2006-01-02T15:04:05.000Z")`, "1500-01-01T00:00:00.250Z"},
+			{`format_time(parse_time("2500-06-30T12:00:00Z") + duration("1h"))`, "2500-06-30T13:00:00Z"},
+			{`format_time(-1)`, "1969-12-31T23:59:59Z"},
+			{`let t = now(); [select([channel()], 60000), now() - t]`, "[[-1, null], 60000]"},
+			{`let t = now(); [select([channel()], duration("1h")), format_time(now())]`, "[[-1, null], 2020-04-01T13:00:00Z]"},
+			{`sleep(-1)`, "ERROR: negative duration passed to `sleep`: -1"},
+			{`sleep(9223372036854775807)`, "ERROR: duration passed to `sleep` out of range: 9223372036854775807"},
+			{`duration("soon")`, "ERROR: invalid duration passed to `duration`: \"soon\""},
+			{`format_time(0, "15:04", "Nowhere/Special")`, "ERROR: unknown time zone passed to `format_time`: \"Nowhere/Special\""},
+			{`parse_time("yesterday")`, "ERROR: invalid time passed to `parse_time`: parsing time \"yesterday\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\""},
+			{`now(1)`, "ERROR: wrong number of arguments to `now`. got=1, want=0"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				e := New(WithClock(&fakeClock{now: start}))
+				evaluated := testEvalWith(t, e, c.input)
+				if evaluated.Inspect() != c.want {
+					t.Errorf("unexpected result. want=%q, got=%q", c.want, evaluated.Inspect())
+				}
+			})
+		}
+	})
+
+	t.Run("FileBuiltins", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  string
+		}{
+			{`read_file("config/app.json")`, `{"debug": true}`},
+			{`json_parse(read_file("./config/app.json"))["debug"]`, "true"},
+			{`list_dir("config")`, "[app.json, db.json]"},
+			{`list_dir()`, "[config, notes.txt]"},
+			{`exists("notes.txt")`, "true"},
+			{`exists("missing.txt")`, "false"},
+			{`write_file("out/report.txt", "done"); read_file("out/report.txt")`, "done"},
+			{`read_file("missing.txt")`, "ERROR: `read_file` failed: open missing.txt: file does not exist"},
+			{`read_file("../etc/passwd")`, "ERROR: invalid path passed to `read_file`: \"../etc/passwd\""},
+			{`write_file("/tmp/x", "x")`, "ERROR: invalid path passed to `write_file`: \"/tmp/x\""},
+			{`write_file("x", 1)`, "ERROR: argument 2 to `write_file` must be STRING, got INTEGER"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				fsys := memFS{
+					"config/app.json": {Data: []byte(`{"debug": true}`)},
+					"config/db.json":  {Data: []byte(`{}`)},
+					"notes.txt":       {Data: []byte("hello")},
+				}
+				e := New(WithFS(fsys), WithWriteFS(fsys))
+				evaluated := testEvalWith(t, e, c.input)
+				if evaluated.Inspect() != c.want {
+					t.Errorf("unexpected result. want=%q, got=%q", c.want, evaluated.Inspect())
+				}
+			})
+		}
+
+		t.Run("Disabled", func(t *testing.T) {
+			for _, input := range []string{`read_file("a")`, `write_file("a", "b")`, `list_dir()`, `exists("a")`} {
+				evaluated := testEval(t, input)
+				errObj, ok := evaluated.(*object.Error)
+				if !ok || !strings.HasPrefix(errObj.Message, "file system access is disabled") {
+					t.Errorf("%s: expected disabled error. got=%s", input, evaluated.Inspect())
+				}
+			}
+		})
+	})
+
+	t.Run("OutputBuiltins", func(t *testing.T) {
+		cases := []struct {
+			input      string
+			wantStdout string
+			wantStderr string
+		}{
+			{`puts("hello", 1, [true])`, "hello\n1\n[true]\n", ""},
+			{`puts()`, "", ""},
+			{`print("a", 1); print("b")`, "a 1b", ""},
+			{`eprint("oops:", 2.5)`, "", "oops: 2.5"},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				var stdout, stderr strings.Builder
+				e := New(WithStdout(&stdout), WithStderr(&stderr))
+				testNullObject(t, testEvalWith(t, e, c.input))
+
+				if stdout.String() != c.wantStdout {
+					t.Errorf("unexpected stdout. want=%q, got=%q", c.wantStdout, stdout.String())
+				}
+
+				if stderr.String() != c.wantStderr {
+					t.Errorf("unexpected stderr. want=%q, got=%q", c.wantStderr, stderr.String())
+				}
+			})
+		}
+	})
+
+	t.Run("Hook", func(t *testing.T) {
+		input := `let double = fn(x) { x * 2 };
+let y = double(len("ab"));
+y`
+
+		h := &recordingHook{}
+		testIntegerObject(t, testEvalWith(t, New(WithHook(h)), input), 4)
+
+		want := []string{
+			"statement let double = fn(x) (x * 2);",
+			"statement let y = double(len(ab));",
+			"call len(ab)",
+			"return len(ab) = 2",
+			"call double(len(ab))",
+			"statement (x * 2)",
+			"return double(len(ab)) = 4",
+			"statement y",
+		}
+		if diff := cmp.Diff(want, h.events); diff != "" {
+			t.Errorf("unexpected hook events\n%s", diff)
+		}
+	})
+
+	t.Run("AssertionBuiltins", func(t *testing.T) {
+		passing := []string{
+			`assert(true)`,
+			`assert(1, "one is truthy")`,
+			`assert_eq(1 + 1, 2)`,
+			`assert_eq(2, 2.0)`,
+			`assert_eq([1, "a", {"b": [true]}], [1, "a", {"b": [true]}])`,
+			`assert_error(fn() { 1 / 0.0; x })`,
+			`assert_error(fn() { x }, "not found")`,
+		}
+
+		for _, input := range passing {
+			testNullObject(t, testEval(t, input))
+		}
+
+		cases := []struct {
+			input     string
+			want      string
+			assertion bool
+		}{
+			{`assert(false)`, "1:1: assert failed: got false", true},
+			{`let x = 1;
+  assert(x > 1, "x is too small")`, "2:3: assert failed: got false: x is too small", true},
+			{`assert_eq("1", 1)`, `1:1: assert_eq failed: got "1", want 1`, true},
+			{`assert_eq([1, 2], [1, 3], "lists")`, "1:1: assert_eq failed: got [1, 2], want [1, 3]: lists", true},
+			{`assert_eq({"a": 1}, {"b": 1})`, `1:1: assert_eq failed: got {a: 1}, want {b: 1}`, true},
+			{`assert_error(fn() { 1 })`, "1:1: assert_error failed: got no error", true},
+			{`assert_error(fn() { x }, "type")`, `1:1: assert_error failed: got error "identifier not found: x", want one containing "type"`, true},
+			{`fn() { assert(false) }()`, "1:8: assert failed: got false", true},
+			{`assert()`, "wrong number of arguments to `assert`. got=0, want=1..2", false},
+			{`assert_eq(1, 1, 1)`, "argument 3 to `assert_eq` must be STRING, got INTEGER", false},
+			{`assert_error(1)`, "argument 1 to `assert_error` must be FUNCTION, got INTEGER", false},
+			{`assert_error(fn(x) { x })`, "function passed to `assert_error` must not take parameters", false},
+		}
+
+		for _, c := range cases {
+			evaluated := testEval(t, c.input)
+			if !testErrorObject(t, evaluated, c.want) {
+				continue
+			}
+			if evaluated.(*object.Error).Assertion != c.assertion {
+				t.Errorf("unexpected assertion flag for %q. got=%t", c.input, !c.assertion)
+			}
+		}
+	})
+
+	t.Run("Scoping", func(t *testing.T) {
+		cases := []struct {
+			input    string
+			expected interface{}
+		}{
+			{"let x = 1; let f = fn() { let y = x; let x = 2; y * 10 + x }; f()", 12},
+			{"let x = 1; let f = fn(c) { if (c) { let x = 2; }; x }; f(false) * 10 + f(true)", 12},
+			{"let counter = fn() { let n = 0; fn() { let n = n + 1; n } }; let c = counter(); c(); c()", 1},
+			{"let f = fn(x, x) { x }; f(1, 2)", 2},
+			{"let f = fn() { let g = fn() { x }; let x = 3; g() }; f()", 3},
+			{"let f = fn(n) { let inner = fn() { n * 2 }; inner() }; f(4) + f(5)", 18},
+			{"let f = fn() { y }; f()", "identifier not found: y"},
+			{"let f = fn() { let g = fn() { late }; g() }; let late = 5; f()", 5},
+		}
+
+		for _, c := range cases {
+			evaluated := testEval(t, c.input)
+			switch expected := c.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case string:
+				testErrorObject(t, evaluated, expected)
+			}
+		}
+	})
+
+	t.Run("TailCalls", func(t *testing.T) {
+		// Without the trampoline, these calls nest far deeper than this stack
+		// allows.
+		defer debug.SetMaxStack(debug.SetMaxStack(16 << 20))
+
+		cases := []struct {
+			input    string
+			expected interface{}
+		}{
+			{"let loop = fn(n) { if (n == 0) { 0 } else { loop(n - 1) } }; loop(100000)", 0},
+			{"let count = fn(n, acc) { if (n == 0) { return acc; } count(n - 1, acc + 1) }; count(100000, 0)", 100000},
+			{`let even = fn(n) { if (n == 0) { return true; } return odd(n - 1); };
+let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+even(100001)`, false},
+			{"let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(100)", 5050},
+			{"let f = fn(x) { len(x) }; f(\"abc\")", 3},
+			{"let adder = fn(x) { fn(y) { x + y } }; let apply = fn(f, v) { f(v) }; apply(adder(2), 3)", 5},
+			{"let f = fn() { g() }; let g = fn() { 1 + true }; f()", "type mismatch: INTEGER + BOOLEAN"},
+			{"let f = fn(n) { let x = if (n > 0) { f(n - 1) } else { 10 }; x + 1 }; f(2)", 13},
+		}
+
+		for _, c := range cases {
+			evaluated := testEval(t, c.input)
+			switch expected := c.expected.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(expected))
+			case bool:
+				testBooleanObject(t, evaluated, expected)
+			case string:
+				testErrorObject(t, evaluated, expected)
+			}
+		}
+	})
+
+	t.Run("EnvironmentReuse", func(t *testing.T) {
+		cases := []struct {
+			input    string
+			expected int64
+		}{
+			{"let f = fn(n) { let a = n * 2; if (n == 0) { a } else { f(n - 1) + a } }; f(3)", 12},
+			{"let g = fn(x) { x + 1 }; let f = fn(x) { let y = g(x); g(y) + x }; f(1) + f(2)", 10},
+			{"let id = fn(x) { x }; let mk = fn(x) { fn() { x } }; let a = mk(1); id(2); let b = mk(3); id(4); a() * 10 + b()", 13},
+			{"let sum = fn(a, b, c, d, e) { a + b + c + d + e }; sum(1, 2, 3, 4, 5) + sum(5000, 0, 0, 0, 1)", 5016},
+			{`let f = fn(s) { len(s + "a") }; f("b") + f(upper("cd"))`, 5},
+		}
+
+		for _, c := range cases {
+			testIntegerObject(t, testEval(t, c.input), c.expected)
+		}
+	})
+
+	t.Run("LongLivedEvaluator", func(t *testing.T) {
+		e := New()
+		env := object.NewEnvironment()
+
+		inputs := []string{
+			`let greet = fn(name) { "hello " + name };`,
+			`let loop = fn(n, s) { if (n == 0) { s } else { loop(n - 1, greet("x")) } };`,
+			`loop(100000, "")`,
+			`greet("y") + greet("z")`,
+		}
+		for _, input := range inputs {
+			program := parser.New(lexer.New(input)).ParseProgram()
+			if result := e.Eval(program, env); isError(result) {
+				t.Fatalf("unexpected error evaluating %q: %s", input, result.Inspect())
+			}
+
+			// Nothing keyed by the nodes of the program is kept once it has
+			// been evaluated.
+			if len(e.strings) != 0 {
+				t.Errorf("string literals kept after evaluating %q: %d", input, len(e.strings))
+			}
+		}
+	})
+
+	t.Run("Allocations", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  uint64
+		}{
+			{"1", 0},
+			{"5000", 1},
+			{"1 + 2", 0},
+			{"5000 + 5000", 3},
+			{"5000 < 6000", 2},
+			{"!true", 0},
+			{`"a" + "b"`, 3},
+			{"[1, 2]", 1},
+			{`{"a": 1}`, 2},
+			{"fn(x) { x }(1)", 2},
+			{"let f = fn(x) { x }; f(1); f(2)", 2},
+			{"let f = fn(x) { fn() { x } }; f(1); f(2)", 5},
+			{"max(5000, 6000)", 2},
+			{`upper("a")`, 2},
+			{"[5000, 6000][0]", 3},
+			{"len([1, 2])", 1},
+		}
+
+		for _, c := range cases {
+			e := New()
+			testEvalWith(t, e, c.input)
+			if got := e.Allocations(); got != c.want {
+				t.Errorf("unexpected allocations for %q. want=%d, got=%d", c.input, c.want, got)
+			}
+		}
+	})
+}
+
+type recordingHook struct {
+	events []string
+}
+
+func (h *recordingHook) Statement(stmt ast.Statement, env *object.Environment) {
+	h.events = append(h.events, "statement "+stmt.String())
+}
+
+func (h *recordingHook) Call(call *ast.CallExpression, fn object.Object, args []object.Object) {
+	h.events = append(h.events, "call "+call.String())
+}
+
+func (h *recordingHook) Return(call *ast.CallExpression, fn object.Object, result object.Object) {
+	h.events = append(h.events, "return "+call.String()+" = "+result.Inspect())
+}
+
+type memFS fstest.MapFS
+
+func (m memFS) Open(name string) (fs.File, error) {
+	return fstest.MapFS(m).Open(name)
+}
+
+func (m memFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m[name] = &fstest.MapFile{Data: data, Mode: perm}
+	return nil
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// After advances the clock by d at once, so the channel it returns is ready.
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	t.Helper()
+
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	t.Helper()
+
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not String. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	t.Helper()
+
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		return false
+	}
+
+	return true
 }
 
 func testNullObject(t *testing.T, obj object.Object) bool {
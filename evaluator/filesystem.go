@@ -0,0 +1,35 @@
+package evaluator
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// WriteFS is the writable counterpart of fs.FS used by the write_file
+// builtin. Names follow the fs.FS conventions: slash-separated, unrooted
+// paths.
+type WriteFS interface {
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+// DirFS returns a file system confined to dir that can be passed to both
+// WithFS and WithWriteFS.
+func DirFS(dir string) interface {
+	fs.FS
+	WriteFS
+} {
+	return &dirFS{FS: os.DirFS(dir), dir: dir}
+}
+
+func (d *dirFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	return os.WriteFile(filepath.Join(d.dir, filepath.FromSlash(name)), data, perm)
+}
@@ -0,0 +1,37 @@
+package evaluator
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirFS(t *testing.T) {
+	dir := t.TempDir()
+	fsys := DirFS(dir)
+
+	if err := fsys.WriteFile("report.txt", []byte("ok"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %s", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "report.txt"))
+	if err != nil {
+		t.Fatalf("file was not written: %s", err)
+	}
+
+	if string(data) != "ok" {
+		t.Errorf("unexpected file content. got=%q", data)
+	}
+
+	data, err = fs.ReadFile(fsys, "report.txt")
+	if err != nil || string(data) != "ok" {
+		t.Errorf("unexpected ReadFile result. got=%q, err=%v", data, err)
+	}
+
+	for _, name := range []string{"../escape.txt", "/abs.txt", "a/../../b.txt"} {
+		if err := fsys.WriteFile(name, []byte("x"), 0644); err == nil {
+			t.Errorf("WriteFile(%q) did not fail", name)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package evaluator
+
+import (
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/object"
+)
+
+// Hook observes an Evaluator running a program. Its methods are called on the
+// goroutine doing the evaluation, which waits for them to return, so that a
+// debugger can pause the program by blocking in them. The functions spawned
+// by the program run without the Hook.
+type Hook interface {
+	// Statement is called before stmt is evaluated in env.
+	Statement(stmt ast.Statement, env *object.Environment)
+	// Call is called before fn is applied to args at call.
+	Call(call *ast.CallExpression, fn object.Object, args []object.Object)
+	// Return is called with the result of the call once it has returned. A
+	// call in tail position replaces the frame of the function making it: the
+	// Return of that frame is called first, with a nil result.
+	Return(call *ast.CallExpression, fn object.Object, result object.Object)
+}
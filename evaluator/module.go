@@ -0,0 +1,144 @@
+package evaluator
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+// ModuleLoader resolves and evaluates the modules named by import
+// expressions. Every module is evaluated at most once; later imports of the
+// same module return the cached value.
+//
+// A ModuleLoader is safe for concurrent use by several Evaluators, such as
+// those of spawned functions: they load modules one at a time, so that an
+// import waits for those of the other Evaluators to finish.
+type ModuleLoader struct {
+	fsys  fs.FS
+	paths []string
+
+	// mu is held by the Evaluator loading modules, while the import
+	// expressions of the modules it loads run.
+	mu sync.Mutex
+
+	cache   map[string]*object.Module
+	loading []string
+}
+
+// NewModuleLoader returns a loader that reads modules from fsys. Import paths
+// starting with "./" or "../" are resolved against the directory of the
+// importing module; any other path is looked up in each of paths in order.
+// Without paths, the root of fsys is searched.
+func NewModuleLoader(fsys fs.FS, paths ...string) *ModuleLoader {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	return &ModuleLoader{
+		fsys:  fsys,
+		paths: paths,
+		cache: make(map[string]*object.Module),
+	}
+}
+
+func (l *ModuleLoader) load(e *Evaluator, name string) object.Object {
+	if e.importing == 0 {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+	}
+	e.importing++
+	defer func() { e.importing-- }()
+
+	modulePath, err := l.resolve(name)
+	if err != nil {
+		return err
+	}
+
+	if module, ok := l.cache[modulePath]; ok {
+		return module
+	}
+
+	for i, p := range l.loading {
+		if p == modulePath {
+			cycle := append(append([]string{}, l.loading[i:]...), modulePath)
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	src, readErr := fs.ReadFile(l.fsys, modulePath)
+	if readErr != nil {
+		return newError("could not read module %s: %s", modulePath, readErr)
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("could not parse module %s: %s", modulePath, strings.Join(p.Errors(), "; "))
+	}
+
+	if e.coverage != nil {
+		e.coverage.Module(modulePath, program)
+	}
+
+	l.loading = append(l.loading, modulePath)
+	env := object.NewEnvironment()
+	result := e.Eval(program, env)
+	l.loading = l.loading[:len(l.loading)-1]
+
+	if isError(result) {
+		return result
+	}
+
+	module := &object.Module{
+		Path:    modulePath,
+		Exports: make(map[string]object.Object),
+	}
+	for _, binding := range env.Names() {
+		if strings.HasPrefix(binding, "_") {
+			continue
+		}
+		module.Exports[binding], _ = env.Get(binding)
+	}
+
+	l.cache[modulePath] = module
+
+	return module
+}
+
+func (l *ModuleLoader) resolve(name string) (string, *object.Error) {
+	if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		dir := "."
+		if len(l.loading) > 0 {
+			dir = path.Dir(l.loading[len(l.loading)-1])
+		}
+
+		modulePath := path.Join(dir, name)
+		if !fs.ValidPath(modulePath) {
+			return "", newError("invalid module path: %s", name)
+		}
+
+		if _, err := fs.Stat(l.fsys, modulePath); err != nil {
+			return "", newError("module not found: %s", name)
+		}
+
+		return modulePath, nil
+	}
+
+	for _, dir := range l.paths {
+		modulePath := path.Join(dir, name)
+		if !fs.ValidPath(modulePath) {
+			continue
+		}
+
+		if _, err := fs.Stat(l.fsys, modulePath); err == nil {
+			return modulePath, nil
+		}
+	}
+
+	return "", newError("module not found: %s", name)
+}
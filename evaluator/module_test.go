@@ -0,0 +1,104 @@
+package evaluator
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+func TestModuleLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"math.monkey": {Data: []byte(`
+let _square = fn(x) { x * x };
+let square = fn(x) { _square(x) };
+let answer = 42;
+`)},
+		"lib/strings.monkey": {Data: []byte(`let greet = fn(name) { "Hello " + name };`)},
+		"lib/relative.monkey": {Data: []byte(`
+let s = import "./strings.monkey";
+let greet = s.greet;
+`)},
+		"cycle/a.monkey": {Data: []byte(`let b = import "./b.monkey";`)},
+		"cycle/b.monkey": {Data: []byte(`let a = import "./a.monkey";`)},
+		"broken.monkey":  {Data: []byte(`let = 1;`)},
+		"failing.monkey": {Data: []byte(`let x = 1 + true;`)},
+	}
+
+	cases := []struct {
+		input string
+		want  interface{}
+	}{
+		{`let m = import "math.monkey"; m.answer`, 42},
+		{`let m = import "math.monkey"; m.square(5)`, 25},
+		{`import "math.monkey".answer`, 42},
+		{`let m = import "math.monkey"; m._square`, "member not found: _square in math.monkey"},
+		{`let s = import "strings.monkey"; s.greet("monkey")`, "Hello monkey"},
+		{`let r = import "lib/relative.monkey"; r.greet("world")`, "Hello world"},
+		{`import "missing.monkey"`, "module not found: missing.monkey"},
+		{`import "../math.monkey"`, "invalid module path: ../math.monkey"},
+		{`import "cycle/a.monkey"`, "import cycle: cycle/a.monkey -> cycle/b.monkey -> cycle/a.monkey"},
+		{`import "broken.monkey"`, "could not parse module broken.monkey: expected next token to be IDENT, got = instead"},
+		{`import "failing.monkey"`, "type mismatch: INTEGER + BOOLEAN"},
+		{`let x = 5; x.y`, "member access not supported: INTEGER"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			e := New(WithModuleLoader(NewModuleLoader(fsys, ".", "lib")))
+			evaluated := testEvalWith(t, e, c.input)
+
+			switch want := c.want.(type) {
+			case int:
+				testIntegerObject(t, evaluated, int64(want))
+			case string:
+				switch got := evaluated.(type) {
+				case *object.String:
+					if got.Value != want {
+						t.Errorf("wrong string. expected=%q, got=%q", want, got.Value)
+					}
+				case *object.Error:
+					if got.Message != want {
+						t.Errorf("wrong error message. expected=%q, got=%q", want, got.Message)
+					}
+				default:
+					t.Errorf("unexpected object. got=%T (%+v)", evaluated, evaluated)
+				}
+			}
+		})
+	}
+
+	t.Run("NoLoader", func(t *testing.T) {
+		want := "cannot import math.monkey: no module loader"
+		testErrorObject(t, testEvalWith(t, New(), `import "math.monkey"`), want)
+	})
+
+	t.Run("Cache", func(t *testing.T) {
+		e := New(WithModuleLoader(NewModuleLoader(fsys)))
+		first := testEvalWith(t, e, `import "math.monkey"`)
+		second := testEvalWith(t, e, `import "./math.monkey"`)
+
+		if _, ok := first.(*object.Module); !ok {
+			t.Fatalf("object is not Module. got=%T (%+v)", first, first)
+		}
+
+		if first != second {
+			t.Errorf("module was evaluated twice. first=%p, second=%p", first, second)
+		}
+	})
+}
+
+func testEvalWith(t *testing.T, e *Evaluator, input string) object.Object {
+	t.Helper()
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %v", p.Errors())
+	}
+
+	return e.Eval(program, object.NewEnvironment())
+}
@@ -0,0 +1,71 @@
+package evaluator
+
+import (
+	"io"
+	"io/fs"
+)
+
+// Option configures an Evaluator created by New.
+type Option func(*Evaluator)
+
+// WithModuleLoader makes the Evaluator resolve imports through l. Evaluators
+// sharing a loader also share its module cache. Without a loader, every
+// import is an error.
+func WithModuleLoader(l *ModuleLoader) Option {
+	return func(e *Evaluator) {
+		e.loader = l
+	}
+}
+
+// WithClock makes the time builtins, and the timeout of select, read and wait
+// on c instead of the system clock.
+func WithClock(c Clock) Option {
+	return func(e *Evaluator) {
+		e.clock = c
+	}
+}
+
+// WithFS lets scripts read files from fsys. Without it, the file builtins
+// report an error instead of touching the host file system.
+func WithFS(fsys fs.FS) Option {
+	return func(e *Evaluator) {
+		e.fsys = fsys
+	}
+}
+
+// WithWriteFS lets scripts write files through w.
+func WithWriteFS(w WriteFS) Option {
+	return func(e *Evaluator) {
+		e.wfs = w
+	}
+}
+
+// WithStdout makes puts and print write to w instead of os.Stdout.
+func WithStdout(w io.Writer) Option {
+	return func(e *Evaluator) {
+		e.stdout = w
+	}
+}
+
+// WithStderr makes eprint write to w instead of os.Stderr.
+func WithStderr(w io.Writer) Option {
+	return func(e *Evaluator) {
+		e.stderr = w
+	}
+}
+
+// WithHook makes the Evaluator report the statements and calls it evaluates
+// to h.
+func WithHook(h Hook) Option {
+	return func(e *Evaluator) {
+		e.hook = h
+	}
+}
+
+// WithCoverage makes the Evaluator report the statements and branches it runs
+// to c.
+func WithCoverage(c Coverage) Option {
+	return func(e *Evaluator) {
+		e.coverage = c
+	}
+}
@@ -0,0 +1,90 @@
+package evaluator
+
+import (
+	"io"
+	"sync"
+
+	"github.com/yagihash/monkey/object"
+)
+
+// fork returns an Evaluator for a function spawned by e, with the same
+// options but a state of its own. They share the module loader, whose loads
+// are serialized, the coverage, which must be safe for concurrent use, and
+// the output, whose writes fork starts serializing. The Hook is not passed
+// on, as it expects the calls and statements of a single goroutine.
+func (e *Evaluator) fork() *Evaluator {
+	if e.output == nil {
+		e.output = &sync.Mutex{}
+		e.stdout = &lockedWriter{mu: e.output, w: e.stdout}
+		e.stderr = &lockedWriter{mu: e.output, w: e.stderr}
+	}
+
+	child := &Evaluator{
+		loader:   e.loader,
+		clock:    e.clock,
+		fsys:     e.fsys,
+		wfs:      e.wfs,
+		stdout:   e.stdout,
+		stderr:   e.stderr,
+		coverage: e.coverage,
+		output:   e.output,
+	}
+	child.init()
+
+	return child
+}
+
+// lockedWriter serializes the writes to w, which it shares with the other
+// lockedWriters holding mu.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (lw *lockedWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	return lw.w.Write(p)
+}
+
+// builtinSpawn calls its first argument with the others on a goroutine of its
+// own and returns a channel receiving the result of the call, closed after.
+// An error of the call is received as is, so that recv fails with it.
+func (e *Evaluator) builtinSpawn(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return newError("wrong number of arguments to `spawn`. got=0, want=1 or more")
+	}
+
+	fn := args[0]
+	switch fn := fn.(type) {
+	case *object.Function:
+		if len(fn.Parameters) != len(args)-1 {
+			return newError("function passed to `spawn` takes %d arguments, got %d", len(fn.Parameters), len(args)-1)
+		}
+	case *object.Builtin:
+	default:
+		return argTypeError("spawn", 0, object.FunctionObj, fn)
+	}
+
+	// The arguments of builtins live on the argument stack of e, which the
+	// goroutine must not read.
+	fnArgs := make([]object.Object, len(args)-1)
+	copy(fnArgs, args[1:])
+
+	for _, arg := range args {
+		object.Share(arg)
+	}
+
+	result := &object.Channel{Chan: make(chan object.Object, 1)}
+	child := e.fork()
+
+	go func() {
+		val := child.callFunction(fn, fnArgs)
+		object.Share(val)
+		result.Chan <- val
+		close(result.Chan)
+	}()
+
+	return result
+}
@@ -0,0 +1,147 @@
+package evaluator
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/yagihash/monkey/coverage"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+func TestSpawn(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"recv(spawn(fn() { 1 + 2 }))", "3"},
+		{"recv(spawn(fn(a, b) { a * b }, 6, 7))", "42"},
+		{`recv(spawn(len, "abc"))`, "3"},
+		{"let r = spawn(fn() { 1 }); recv(r); recv(r)", "null"},
+		{"recv(spawn(fn() { 1 + true })); 2", "ERROR: type mismatch: INTEGER + BOOLEAN"},
+		{"let work = fn(n) { n * n }; let rs = [spawn(work, 1), spawn(work, 2), spawn(work, 3)]; recv(rs[0]) + recv(rs[1]) + recv(rs[2])", "14"},
+		{"let f = fn(n) { fn() { n * 2 } }; recv(spawn(f(21)))", "42"},
+		{"let c = channel(); let r = spawn(fn() { recv(c); later }); let later = 5; send(c, 1); recv(r)", "5"},
+		{"spawn(fn(x) { x })", "ERROR: function passed to `spawn` takes 1 arguments, got 0"},
+		{"spawn(1)", "ERROR: argument 1 to `spawn` must be FUNCTION, got INTEGER"},
+		{"spawn()", "ERROR: wrong number of arguments to `spawn`. got=0, want=1 or more"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			if got := testEval(t, c.input).Inspect(); got != c.want {
+				t.Errorf("unexpected result. want=%q, got=%q", c.want, got)
+			}
+		})
+	}
+}
+
+func TestChannels(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"let c = channel(1); send(c, 5); recv(c)", "5"},
+		{"let c = channel(); spawn(fn() { send(c, 1); send(c, 2); close(c) }); [recv(c), recv(c), recv(c)]", "[1, 2, null]"},
+		{"channel(2)", "channel(2)"},
+		{"let c = channel(); close(c); recv(c)", "null"},
+		{"let c = channel(); close(c); close(c)", "ERROR: close of closed channel"},
+		{"let c = channel(1); close(c); send(c, 1)", "ERROR: send on closed channel"},
+		{"channel(-1)", "ERROR: negative capacity passed to `channel`: -1"},
+		{"send(1, 2)", "ERROR: argument 1 to `send` must be CHANNEL, got INTEGER"},
+		{"recv()", "ERROR: wrong number of arguments to `recv`. got=0, want=1"},
+		{"let a = channel(1); let b = channel(1); send(b, 7); select([a, b])", "[1, 7]"},
+		{"let a = channel(1); [select([[a, 3]]), recv(a)]", "[[0, null], 3]"},
+		{"let a = channel(); close(a); select([a])", "[0, null]"},
+		{"let a = channel(); select([a], 0)", "[-1, null]"},
+		{"let a = channel(); let b = channel(); spawn(fn() { send(b, 4) }); select([a, b], 1000)", "[1, 4]"},
+		{"let a = channel(1); close(a); select([[a, 1]])", "ERROR: send on closed channel"},
+		{"select([1])", "ERROR: case 0 passed to `select` must be CHANNEL or [CHANNEL, value], got INTEGER"},
+		{"select([[1, 2]])", "ERROR: case 0 passed to `select` must be CHANNEL or [CHANNEL, value], got ARRAY"},
+		{"select([])", "ERROR: no cases passed to `select`"},
+		{"select([], -1)", "ERROR: negative timeout passed to `select`: -1"},
+		{"select([], 9223372036854775807)", "ERROR: timeout passed to `select` out of range: 9223372036854775807"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			if got := testEval(t, c.input).Inspect(); got != c.want {
+				t.Errorf("unexpected result. want=%q, got=%q", c.want, got)
+			}
+		})
+	}
+}
+
+// TestSpawnRace runs workers sharing closures, globals written after they
+// start, modules, output and coverage. Run it with -race.
+func TestSpawnRace(t *testing.T) {
+	fsys := fstest.MapFS{
+		"math.monkey": {Data: []byte(`let square = fn(x) { x * x };`)},
+	}
+	input := `
+let results = channel(8);
+let worker = fn(i) {
+  let m = import "math.monkey";
+  let plus = fn(x) { x + base };
+  puts(i);
+  send(results, m.square(i) + plus(m.square(i)))
+};
+let base = 1;
+let start = fn(i) { if (i == 0) { 0 } else { spawn(worker, i); start(i - 1) } };
+start(8);
+let later = fn() { base };
+let sum = fn(i, acc) { if (i == 0) { acc } else { sum(i - 1, acc + recv(results)) } };
+sum(8, later() - 1)
+`
+
+	var stdout bytes.Buffer
+	cov := coverage.New("")
+	e := New(
+		WithModuleLoader(NewModuleLoader(fsys)),
+		WithStdout(&stdout),
+		WithCoverage(cov),
+	)
+
+	program := parser.New(lexer.New(input)).ParseProgram()
+	cov.Add("race.monkey", program)
+	testIntegerObject(t, e.Eval(program, object.NewEnvironment()), 416)
+
+	if got := strings.Count(stdout.String(), "\n"); got != 8 {
+		t.Errorf("unexpected number of lines printed. want=8, got=%d", got)
+	}
+	for _, f := range cov.Files() {
+		if covered, total := f.Statements(); covered != total {
+			t.Errorf("unexpected statement coverage of %s. want=%d, got=%d", f.Name, total, covered)
+		}
+	}
+}
+
+// TestProgramRace evaluates one parsed program from several goroutines, each
+// with an Evaluator and an environment of its own. Run it with -race.
+func TestProgramRace(t *testing.T) {
+	program := parser.New(lexer.New(`
+let name = "worker";
+let add = fn(x, y) { x + y };
+let adder = fn(n) { fn(x) { add(x, n) } };
+let count = fn(i, acc) { if (i == 0) { acc } else { count(i - 1, adder(1)(acc)) } };
+len(name) + count(100, 0)
+`)).ParseProgram()
+
+	const workers = 16
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if got := New().Eval(program, object.NewEnvironment()).Inspect(); got != "106" {
+				t.Errorf("unexpected result of worker %d. want=%q, got=%q", i, "106", got)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
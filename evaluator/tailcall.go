@@ -0,0 +1,22 @@
+package evaluator
+
+import (
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/object"
+)
+
+// tailCall is a call in tail position whose function and arguments have been
+// evaluated, returned for callFunction to make.
+type tailCall struct {
+	call *ast.CallExpression
+	fn   object.Object
+	args []object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType {
+	return "TAIL_CALL"
+}
+
+func (tc *tailCall) Inspect() string {
+	return "tail call"
+}
@@ -7,19 +7,37 @@ type Lexer struct {
 	position     int
 	readPosition int
 	ch           byte
+
+	// line and column locate ch in the input.
+	line   int
+	column int
 }
 
 func New(input string) *Lexer {
 	l := &Lexer{
 		input: input,
+		line:  1,
 	}
 	l.readChar()
 	return l
 }
 
-func (l *Lexer) NextToken() (tok token.Token) {
+func (l *Lexer) NextToken() token.Token {
 	l.skipWhitespace()
 
+	pos := l.pos()
+	tok := l.nextToken()
+	tok.Pos = pos
+	tok.End = l.pos()
+
+	return tok
+}
+
+func (l *Lexer) pos() token.Position {
+	return token.Position{Offset: l.position, Line: l.line, Column: l.column}
+}
+
+func (l *Lexer) nextToken() (tok token.Token) {
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -51,12 +69,20 @@ func (l *Lexer) NextToken() (tok token.Token) {
 		tok = newToken(token.GT, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
+	case ':':
+		tok = newToken(token.COLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case '.':
+		tok = newToken(token.DOT, l.ch)
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
 		tok = newToken(token.RBRACE, l.ch)
+	case '[':
+		tok = newToken(token.LBRACKET, l.ch)
+	case ']':
+		tok = newToken(token.RBRACKET, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -67,15 +93,18 @@ func (l *Lexer) NextToken() (tok token.Token) {
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
+		// The EOF token is empty, at the end of the input, however many
+		// times it is read.
+		if l.position >= len(l.input) {
+			return tok
+		}
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
-			return tok
+			return l.readNumber()
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
@@ -101,6 +130,11 @@ func (l *Lexer) peekChar() byte {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -108,14 +142,24 @@ func (l *Lexer) readChar() {
 	}
 	l.position = l.readPosition
 	l.readPosition += 1
+	l.column += 1
 }
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) readNumber() token.Token {
 	pos := l.position
 	for isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[pos:l.position]
+
+	if l.ch != '.' || !isDigit(l.peekChar()) {
+		return token.Token{Type: token.INT, Literal: l.input[pos:l.position]}
+	}
+
+	l.readChar()
+	for isDigit(l.ch) {
+		l.readChar()
+	}
+	return token.Token{Type: token.FLOAT, Literal: l.input[pos:l.position]}
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -29,6 +29,8 @@ if (5 < 10) {
 10 != 9;
 "foobar"
 "foo bar"
+[1, 2.5];
+import "m".x
 `
 	cases := []struct {
 		name string
@@ -334,21 +336,122 @@ if (5 < 10) {
 			name: "foo bar",
 			want: token.Token{Type: token.STRING, Literal: "foo bar"},
 		},
+		{
+			name: "[1, 2.5];",
+			want: token.Token{Type: token.LBRACKET, Literal: "["},
+		},
+		{
+			name: "[1, 2.5];",
+			want: token.Token{Type: token.INT, Literal: "1"},
+		},
+		{
+			name: "[1, 2.5];",
+			want: token.Token{Type: token.COMMA, Literal: ","},
+		},
+		{
+			name: "[1, 2.5];",
+			want: token.Token{Type: token.FLOAT, Literal: "2.5"},
+		},
+		{
+			name: "[1, 2.5];",
+			want: token.Token{Type: token.RBRACKET, Literal: "]"},
+		},
+		{
+			name: "[1, 2.5];",
+			want: token.Token{Type: token.SEMICOLON, Literal: ";"},
+		},
+		{
+			name: `import "m".x`,
+			want: token.Token{Type: token.IMPORT, Literal: "import"},
+		},
+		{
+			name: `import "m".x`,
+			want: token.Token{Type: token.STRING, Literal: "m"},
+		},
+		{
+			name: `import "m".x`,
+			want: token.Token{Type: token.DOT, Literal: "."},
+		},
+		{
+			name: `import "m".x`,
+			want: token.Token{Type: token.IDENT, Literal: "x"},
+		},
 		{
 			name: "EOF",
 			want: token.Token{Type: token.EOF, Literal: ""},
 		},
 	}
 
+	ignorePositions := cmp.FilterPath(func(p cmp.Path) bool {
+		return p.String() == "Pos" || p.String() == "End"
+	}, cmp.Ignore())
 	l := New(input)
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			got := l.NextToken()
 
-			if diff := cmp.Diff(got, c.want); diff != "" {
+			if diff := cmp.Diff(got, c.want, ignorePositions); diff != "" {
 				t.Errorf("unexpected return value\n%s", diff)
 			}
 		})
 	}
 }
+
+func TestNextToken_Position(t *testing.T) {
+	input := "let x = 10;\n  \"a b\" == x\n"
+
+	cases := []struct {
+		literal  string
+		pos, end token.Position
+	}{
+		{literal: "let", pos: token.Position{Offset: 0, Line: 1, Column: 1}, end: token.Position{Offset: 3, Line: 1, Column: 4}},
+		{literal: "x", pos: token.Position{Offset: 4, Line: 1, Column: 5}, end: token.Position{Offset: 5, Line: 1, Column: 6}},
+		{literal: "=", pos: token.Position{Offset: 6, Line: 1, Column: 7}, end: token.Position{Offset: 7, Line: 1, Column: 8}},
+		{literal: "10", pos: token.Position{Offset: 8, Line: 1, Column: 9}, end: token.Position{Offset: 10, Line: 1, Column: 11}},
+		{literal: ";", pos: token.Position{Offset: 10, Line: 1, Column: 11}, end: token.Position{Offset: 11, Line: 1, Column: 12}},
+		{literal: "a b", pos: token.Position{Offset: 14, Line: 2, Column: 3}, end: token.Position{Offset: 19, Line: 2, Column: 8}},
+		{literal: "==", pos: token.Position{Offset: 20, Line: 2, Column: 9}, end: token.Position{Offset: 22, Line: 2, Column: 11}},
+		{literal: "x", pos: token.Position{Offset: 23, Line: 2, Column: 12}, end: token.Position{Offset: 24, Line: 2, Column: 13}},
+		{literal: "", pos: token.Position{Offset: 25, Line: 3, Column: 1}, end: token.Position{Offset: 25, Line: 3, Column: 1}},
+		{literal: "", pos: token.Position{Offset: 25, Line: 3, Column: 1}, end: token.Position{Offset: 25, Line: 3, Column: 1}},
+	}
+
+	l := New(input)
+
+	for _, c := range cases {
+		got := l.NextToken()
+
+		if got.Literal != c.literal {
+			t.Fatalf("unexpected literal. want=%q, got=%q", c.literal, got.Literal)
+		}
+
+		if diff := cmp.Diff(c.pos, got.Pos); diff != "" {
+			t.Errorf("unexpected position of %q\n%s", c.literal, diff)
+		}
+
+		if diff := cmp.Diff(c.end, got.End); diff != "" {
+			t.Errorf("unexpected end of %q\n%s", c.literal, diff)
+		}
+	}
+}
+
+func TestNextToken_EOF(t *testing.T) {
+	for _, input := range []string{"", "x", "let a = 1;\n", "\"abc\"  "} {
+		t.Run(input, func(t *testing.T) {
+			l := New(input)
+
+			tok := l.NextToken()
+			for tok.Type != token.EOF {
+				tok = l.NextToken()
+			}
+
+			if tok.Pos.Offset != len(input) || tok.End.Offset != len(input) {
+				t.Errorf("unexpected offsets of EOF. want=%d, got=%d..%d", len(input), tok.Pos.Offset, tok.End.Offset)
+			}
+			if got := input[tok.Pos.Offset:tok.End.Offset]; got != "" {
+				t.Errorf("unexpected text of EOF. got=%q", got)
+			}
+		})
+	}
+}
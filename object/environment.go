@@ -1,10 +1,41 @@
 package object
 
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Environment binds names to values. The environment of a function call keeps
+// the variables the resolver assigned to the function in slots, one for each
+// of its local names, and any other variable in a map.
+//
+// An environment is only written by the goroutine evaluating the code that
+// created it. Other goroutines read it once a closure referring to it has
+// been passed to them, after Share marked it shared: from then on, its reads
+// and writes are locked. A frozen environment is read-only instead, so that
+// any number of goroutines can read it without locking.
 type Environment struct {
+	mu    sync.RWMutex
+	state int32
+
 	store map[string]Object
+	names []string
+	slots []Object
 	outer *Environment
+
+	// inline holds the slots of functions with few local names, saving
+	// their allocation.
+	inline [4]Object
 }
 
+// The states of an environment, changed by Share and Freeze only.
+const (
+	owned int32 = iota
+	shared
+	frozen
+)
+
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
 	return &Environment{store: s}
@@ -16,15 +47,196 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	return inner
 }
 
+// NewFunctionEnvironment returns an environment enclosed by outer with an
+// empty slot for each of names, which must be distinct.
+func NewFunctionEnvironment(outer *Environment, names []string) *Environment {
+	env := &Environment{names: names, outer: outer}
+	if len(names) <= len(env.inline) {
+		env.slots = env.inline[:len(names)]
+	} else {
+		env.slots = make([]Object, len(names))
+	}
+	return env
+}
+
+// Reset empties e and makes it the environment NewFunctionEnvironment would
+// return for outer and names, reusing its storage. Nothing may refer to e
+// anymore: it is meant for the environments of calls that have returned
+// without creating a closure.
+func (e *Environment) Reset(outer *Environment, names []string) {
+	for i := range e.slots {
+		e.slots[i] = nil
+	}
+	for name := range e.store {
+		delete(e.store, name)
+	}
+
+	if len(names) <= cap(e.slots) {
+		e.slots = e.slots[:len(names)]
+	} else {
+		e.slots = make([]Object, len(names))
+	}
+	e.names = names
+	e.outer = outer
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
+	obj, ok := e.get(name)
 	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
 	return obj, ok
 }
 
+func (e *Environment) get(name string) (Object, bool) {
+	if e.isShared() {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+	}
+
+	for i, n := range e.names {
+		if n == name && e.slots[i] != nil {
+			return e.slots[i], true
+		}
+	}
+
+	obj, ok := e.store[name]
+	return obj, ok
+}
+
+// Set binds name to val in e. It panics if e is frozen.
 func (e *Environment) Set(name string, val Object) Object {
+	switch atomic.LoadInt32(&e.state) {
+	case frozen:
+		panic("object: write to a frozen environment")
+	case shared:
+		Share(val)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+	}
+
+	for i, n := range e.names {
+		if n == name {
+			e.slots[i] = val
+			return val
+		}
+	}
+
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+// Slot returns the value in slot i, or nil if it has not been set.
+func (e *Environment) Slot(i int) Object {
+	if e.isShared() {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+	}
+
+	return e.slots[i]
+}
+
+// SetSlot sets slot i to val. It panics if e is frozen.
+func (e *Environment) SetSlot(i int, val Object) Object {
+	switch atomic.LoadInt32(&e.state) {
+	case frozen:
+		panic("object: write to a frozen environment")
+	case shared:
+		Share(val)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+	}
+
+	e.slots[i] = val
+	return val
+}
+
+// Outer returns the environment enclosing e, or nil for a global environment.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Names returns the sorted names bound directly in e, ignoring outer scopes.
+func (e *Environment) Names() []string {
+	if e.isShared() {
+		e.mu.RLock()
+		defer e.mu.RUnlock()
+	}
+
+	names := make([]string, 0, len(e.store)+len(e.slots))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	for i, name := range e.names {
+		if e.slots[i] != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Frozen reports whether e has been frozen by Freeze.
+func (e *Environment) Frozen() bool {
+	return atomic.LoadInt32(&e.state) == frozen
+}
+
+func (e *Environment) isShared() bool {
+	return atomic.LoadInt32(&e.state) == shared
+}
+
+// Freeze makes e, the environments enclosing it and those the values bound
+// in them can reach read-only, so that they can be read by any number of
+// goroutines, typically through environments of their own enclosed by e.
+// Nothing may be writing them when Freeze is called.
+func (e *Environment) Freeze() {
+	e.mark(frozen)
+}
+
+// mark sets the state of e and the environments enclosing it, along with
+// those the values bound in them can reach, to state. It stops at the
+// environments that are no longer owned, since those enclosing them and the
+// values bound in them have been marked already.
+func (e *Environment) mark(state int32) {
+	for env := e; env != nil; env = env.outer {
+		if atomic.LoadInt32(&env.state) != owned {
+			return
+		}
+		atomic.StoreInt32(&env.state, state)
+
+		for _, val := range env.slots {
+			markEnvironments(val, state)
+		}
+		for _, val := range env.store {
+			markEnvironments(val, state)
+		}
+	}
+}
+
+// Share marks the environments obj can reach through the closures it holds
+// as shared, so that obj can be passed to another goroutine. It must be
+// called by the goroutine obj was passed to last. Frozen environments are
+// left as they are, as they can be read without locking already.
+func Share(obj Object) {
+	markEnvironments(obj, shared)
+}
+
+func markEnvironments(obj Object, state int32) {
+	switch obj := obj.(type) {
+	case *Function:
+		obj.Env.mark(state)
+	case *Array:
+		for _, el := range obj.Elements {
+			markEnvironments(el, state)
+		}
+	case *Hash:
+		for _, pair := range obj.Pairs {
+			markEnvironments(pair.Value, state)
+		}
+	case *ReturnValue:
+		markEnvironments(obj.Value, state)
+	}
+}
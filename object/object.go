@@ -3,6 +3,9 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/yagihash/monkey/ast"
@@ -12,6 +15,7 @@ type ObjectType string
 
 const (
 	IntegerObj     = "INTEGER"
+	FloatObj       = "FLOAT"
 	StringObj      = "STRING"
 	BoolenaObj     = "BOOLEAN"
 	NullObj        = "NULL"
@@ -19,6 +23,10 @@ const (
 	ReturnValueObj = "RETURN_VALUE"
 	FunctionObj    = "FUNCTION"
 	BuiltinObj     = "BUILTIN"
+	ModuleObj      = "MODULE"
+	ArrayObj       = "ARRAY"
+	HashObj        = "HASH"
+	ChannelObj     = "CHANNEL"
 )
 
 type Object interface {
@@ -38,6 +46,22 @@ func (i Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+type Float struct {
+	Value float64
+}
+
+func (f Float) Type() ObjectType {
+	return FloatObj
+}
+
+func (f Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eInN") {
+		return s
+	}
+	return s + ".0"
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -62,6 +86,9 @@ func (n Null) Inspect() string {
 
 type Error struct {
 	Message string
+	// Assertion tells that the error is a failed assertion of a test rather
+	// than a failure of the program.
+	Assertion bool
 }
 
 func (e Error) Type() ObjectType {
@@ -88,6 +115,10 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	// Locals names the slots of the environments of the function's calls.
+	Locals []string
+	// Leaf tells that no function literal appears in Body.
+	Leaf bool
 }
 
 func (f Function) Type() ObjectType {
@@ -137,3 +168,109 @@ func (b Builtin) Type() ObjectType {
 func (b Builtin) Inspect() string {
 	return "fn() { builtin function }"
 }
+
+type Module struct {
+	Path    string
+	Exports map[string]Object
+}
+
+func (m Module) Type() ObjectType {
+	return ModuleObj
+}
+
+func (m Module) Inspect() string {
+	return "module(" + m.Path + ")"
+}
+
+// Channel passes values between the goroutines of spawned functions.
+type Channel struct {
+	Chan chan Object
+}
+
+func (c Channel) Type() ObjectType {
+	return ChannelObj
+}
+
+func (c Channel) Inspect() string {
+	return fmt.Sprintf("channel(%d)", cap(c.Chan))
+}
+
+type Array struct {
+	Elements []Object
+}
+
+func (a Array) Type() ObjectType {
+	return ArrayObj
+}
+
+func (a Array) Inspect() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, e.Inspect())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the objects that can be used as hash keys.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+func (b Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (i Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (s String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs map[HashKey]HashPair
+}
+
+func (h Hash) Type() ObjectType {
+	return HashObj
+}
+
+func (h Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+	sort.Strings(pairs)
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,171 @@
+package optimize
+
+import (
+	"strconv"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/token"
+)
+
+// foldPrefix returns the literal pe evaluates to, or pe if its operand is not
+// a constant or applying the operator to it fails.
+func foldPrefix(pe *ast.PrefixExpression) ast.Expression {
+	switch pe.Operator {
+	case "!":
+		switch right := pe.Right.(type) {
+		case *ast.Boolean:
+			return newBoolean(!right.Value, pe.Token.Pos, right.Token.End)
+		case *ast.IntegerLiteral, *ast.StringLiteral:
+			// Every value but false and null is truthy.
+			return newBoolean(false, pe.Token.Pos, end(right))
+		}
+	case "-":
+		if right, ok := pe.Right.(*ast.IntegerLiteral); ok {
+			return newInteger(-right.Value, pe.Token.Pos, right.Token.End)
+		}
+	}
+
+	return pe
+}
+
+// foldInfix returns the literal ie evaluates to, or ie if its operands are
+// not constants or applying the operator to them fails.
+func foldInfix(ie *ast.InfixExpression) ast.Expression {
+	if !isConstant(ie.Left) || !isConstant(ie.Right) {
+		return ie
+	}
+	pos, end := start(ie.Left), end(ie.Right)
+
+	switch left := ie.Left.(type) {
+	case *ast.IntegerLiteral:
+		if right, ok := ie.Right.(*ast.IntegerLiteral); ok {
+			return foldIntegers(ie, left.Value, right.Value, pos, end)
+		}
+	case *ast.StringLiteral:
+		if right, ok := ie.Right.(*ast.StringLiteral); ok {
+			switch ie.Operator {
+			case "+":
+				return newString(left.Value+right.Value, pos, end)
+			case "==":
+				return newBoolean(left.Value == right.Value, pos, end)
+			case "!=":
+				return newBoolean(left.Value != right.Value, pos, end)
+			}
+			return ie
+		}
+	case *ast.Boolean:
+		if right, ok := ie.Right.(*ast.Boolean); ok {
+			switch ie.Operator {
+			case "==":
+				return newBoolean(left.Value == right.Value, pos, end)
+			case "!=":
+				return newBoolean(left.Value != right.Value, pos, end)
+			}
+			return ie
+		}
+	}
+
+	// Values of different types are never equal.
+	switch ie.Operator {
+	case "==":
+		return newBoolean(false, pos, end)
+	case "!=":
+		return newBoolean(true, pos, end)
+	}
+
+	return ie
+}
+
+func foldIntegers(ie *ast.InfixExpression, left, right int64, pos, end token.Position) ast.Expression {
+	switch ie.Operator {
+	case "+":
+		return newInteger(left+right, pos, end)
+	case "-":
+		return newInteger(left-right, pos, end)
+	case "*":
+		return newInteger(left*right, pos, end)
+	case "/":
+		if right == 0 {
+			return ie
+		}
+		return newInteger(left/right, pos, end)
+	case "<":
+		return newBoolean(left < right, pos, end)
+	case ">":
+		return newBoolean(left > right, pos, end)
+	case "==":
+		return newBoolean(left == right, pos, end)
+	case "!=":
+		return newBoolean(left != right, pos, end)
+	}
+
+	return ie
+}
+
+// constantCondition reports whether exp is a constant and, if so, whether it
+// is truthy.
+func constantCondition(exp ast.Expression) (truthy, ok bool) {
+	switch exp := exp.(type) {
+	case *ast.Boolean:
+		return exp.Value, true
+	case *ast.IntegerLiteral, *ast.StringLiteral:
+		return true, true
+	}
+	return false, false
+}
+
+func isConstant(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	}
+	return false
+}
+
+// start and end return the positions spanned by a constant.
+func start(exp ast.Expression) token.Position {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return exp.Token.Pos
+	case *ast.StringLiteral:
+		return exp.Token.Pos
+	case *ast.Boolean:
+		return exp.Token.Pos
+	}
+	return token.Position{}
+}
+
+func end(exp ast.Expression) token.Position {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return exp.Token.End
+	case *ast.StringLiteral:
+		return exp.Token.End
+	case *ast.Boolean:
+		return exp.Token.End
+	}
+	return token.Position{}
+}
+
+func newInteger(value int64, pos, end token.Position) *ast.IntegerLiteral {
+	literal := strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{
+		Token: token.Token{Type: token.INT, Literal: literal, Pos: pos, End: end},
+		Value: value,
+	}
+}
+
+func newString(value string, pos, end token.Position) *ast.StringLiteral {
+	return &ast.StringLiteral{
+		Token: token.Token{Type: token.STRING, Literal: value, Pos: pos, End: end},
+		Value: value,
+	}
+}
+
+func newBoolean(value bool, pos, end token.Position) *ast.Boolean {
+	tok := token.Token{Type: token.FALSE, Literal: "false", Pos: pos, End: end}
+	if value {
+		tok.Type, tok.Literal = token.TRUE, "true"
+	}
+	return &ast.Boolean{Token: tok, Value: value}
+}
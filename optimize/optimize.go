@@ -0,0 +1,163 @@
+// Package optimize rewrites programs so that they do less work when
+// evaluated, without changing their results.
+//
+// It folds the operators whose operands are integer, string or boolean
+// literals into the literal they evaluate to, keeps only the branch an if
+// expression with a constant condition takes, drops the statements following
+// a return statement, and removes the let statements of function bodies that
+// bind a variable nothing uses to a value that is computed without side
+// effects.
+//
+// Expressions whose evaluation fails, such as a division by zero or an
+// operator applied to the wrong types, are left alone, so that the error is
+// still reported when the program runs. Let statements at the top level of a
+// program are kept, since modules export them and the REPL and the test
+// runner look them up by name.
+package optimize
+
+import (
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/resolver"
+)
+
+// Optimize rewrites program in place. A program that was already resolved is
+// resolved again, as removing let statements moves the slots of the
+// variables of functions.
+func Optimize(program *ast.Program) {
+	program.Statements = statements(program.Statements)
+
+	if program.Resolved {
+		program.Resolved = false
+		resolver.Resolve(program)
+	}
+}
+
+// statements optimizes the statements of a program or block. The value of
+// the list, that of its last statement, is preserved.
+func statements(stmts []ast.Statement) []ast.Statement {
+	out := make([]ast.Statement, 0, len(stmts))
+
+	for i, stmt := range stmts {
+		last := i == len(stmts)-1
+
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			stmt.Value = expression(stmt.Value)
+		case *ast.ReturnStatement:
+			stmt.ReturnValue = expression(stmt.ReturnValue)
+			// Nothing after a return statement runs.
+			return append(out, stmt)
+		case *ast.ExpressionStatement:
+			stmt.Expression = expression(stmt.Expression)
+
+			ie, ok := stmt.Expression.(*ast.IfExpression)
+			if !ok {
+				break
+			}
+			truthy, ok := constantCondition(ie.Condition)
+			if !ok {
+				break
+			}
+
+			// Blocks do not open a scope, so the statements of the branch
+			// taken can replace the if expression. An empty branch is kept
+			// in last position only, where its value is that of the list.
+			taken := ie.Consequence
+			if !truthy {
+				taken = ie.Alternative
+			}
+			if taken == nil || len(taken.Statements) == 0 {
+				if last {
+					break
+				}
+				continue
+			}
+
+			out = append(out, taken.Statements...)
+			if _, ok := taken.Statements[len(taken.Statements)-1].(*ast.ReturnStatement); ok {
+				return out
+			}
+			continue
+		}
+
+		out = append(out, stmt)
+	}
+
+	return out
+}
+
+func block(b *ast.BlockStatement) {
+	if b != nil {
+		b.Statements = statements(b.Statements)
+	}
+}
+
+func expression(exp ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.PrefixExpression:
+		exp.Right = expression(exp.Right)
+		return foldPrefix(exp)
+	case *ast.InfixExpression:
+		exp.Left = expression(exp.Left)
+		exp.Right = expression(exp.Right)
+		return foldInfix(exp)
+	case *ast.IfExpression:
+		return ifExpression(exp)
+	case *ast.FunctionLiteral:
+		block(exp.Body)
+		removeUnusedLets(exp)
+	case *ast.CallExpression:
+		exp.Function = expression(exp.Function)
+		for i, arg := range exp.Arguments {
+			exp.Arguments[i] = expression(arg)
+		}
+	case *ast.MemberExpression:
+		exp.Object = expression(exp.Object)
+	case *ast.ArrayLiteral:
+		for i, el := range exp.Elements {
+			exp.Elements[i] = expression(el)
+		}
+	case *ast.IndexExpression:
+		exp.Left = expression(exp.Left)
+		exp.Index = expression(exp.Index)
+	case *ast.HashLiteral:
+		for i, pair := range exp.Pairs {
+			exp.Pairs[i].Key = expression(pair.Key)
+			exp.Pairs[i].Value = expression(pair.Value)
+		}
+	}
+
+	return exp
+}
+
+// ifExpression drops the branch an if expression with a constant condition
+// does not take. If the branch taken is a single expression, it replaces the
+// if expression.
+func ifExpression(ie *ast.IfExpression) ast.Expression {
+	ie.Condition = expression(ie.Condition)
+	block(ie.Consequence)
+	block(ie.Alternative)
+
+	truthy, ok := constantCondition(ie.Condition)
+	if !ok {
+		return ie
+	}
+
+	if truthy {
+		ie.Alternative = nil
+	} else {
+		ie.Consequence = &ast.BlockStatement{Token: ie.Consequence.Token}
+	}
+
+	taken := ie.Consequence
+	if !truthy {
+		taken = ie.Alternative
+	}
+	if taken != nil && len(taken.Statements) == 1 {
+		if es, ok := taken.Statements[0].(*ast.ExpressionStatement); ok {
+			return es.Expression
+		}
+	}
+
+	return ie
+}
@@ -0,0 +1,152 @@
+package optimize
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+func TestOptimize(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		// Constant folding.
+		{"2 * 60 * 60", "7200"},
+		{"1 + 2 * 3 - -4", "11"},
+		{"7 / 2", "3"},
+		{`"a" + "b" + "c"`, "abc"},
+		{`"a" == "a"`, "true"},
+		{"1 < 2 == true", "true"},
+		{"!(1 > 2)", "true"},
+		{`!"a"`, "false"},
+		{`1 == "1"`, "false"},
+		{"true != 1", "true"},
+		{"x * (2 + 3)", "(x * 5)"},
+		{"[1 + 1, {2 * 2: 3 - 3}][0]", "([2, {4: 0}][0])"},
+
+		// Failures are left for the evaluator to report.
+		{"1 / 0", "(1 / 0)"},
+		{"10 / (5 - 5)", "(10 / 0)"},
+		{"1 + true", "(1 + true)"},
+		{`"a" - "b"`, "(a - b)"},
+		{"-true", "(-true)"},
+		{"true < false", "(true < false)"},
+
+		// Dead branches.
+		{"if (true) { 1 } else { 2 }", "1"},
+		{"if (1 > 2) { 1 } else { 2 }", "2"},
+		{"let x = if (false) { 1 }", "let x = iffalse ;"},
+		{"if (true) { let a = 1; a }; a", "let a = 1;aa"},
+		{"if (false) { let a = 1; }; 2", "2"},
+		{"if (false) { 1 }", "iffalse "},
+		{"if (c) { 1 + 1 } else { 2 + 2 }", "ifc 2else 4"},
+
+		// Statements after return.
+		{"return 1; 2; 3", "return 1;"},
+		{"let f = fn(x) { return 1; 2 }", "let f = fn(x) return 1;;"},
+		{"if (true) { return 1; }; 2", "return 1;"},
+
+		// Unused lets.
+		{"let f = fn(x) { let a = 1; let b = fn(x) { 2 }; x }", "let f = fn(x) x;"},
+		{"let f = fn(x) { let a = 1; let b = fn(x) { a }; 3 }", "let f = fn(x) 3;"},
+		{"let f = fn(x) { let a = 1; let g = fn(x) { a }; g() }", "let f = fn(x) let a = 1;let g = fn(x) a;g();"},
+		{"let f = fn(x) { let a = g(); 1 }", "let f = fn(x) let a = g();1;"},
+		{"let f = fn(x) { let a = [b]; 1 }", "let f = fn(x) let a = [b];1;"},
+		{"let f = fn(x) { let a = {[1]: 2}; 1 }", "let f = fn(x) let a = {[1]: 2};1;"},
+		{"let f = fn(x) { let a = 1 / 0; 1 }", "let f = fn(x) let a = (1 / 0);1;"},
+		{"let f = fn(x) { let a = m.a; 1 }", "let f = fn(x) let a = (m.a);1;"},
+		{"let f = fn(x) { 1; let a = 2 }", "let f = fn(x) 1let a = 2;;"},
+		{"let f = fn(c) { if (c) { let a = 1; 2 } }", "let f = fn(c) ifc 2;"},
+		{"let a = 1; 2", "let a = 1;2"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			program := parse(t, c.input)
+			Optimize(program)
+
+			if diff := cmp.Diff(c.expected, program.String()); diff != "" {
+				t.Errorf("unexpected program\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOptimizePreservesResults(t *testing.T) {
+	inputs := []string{
+		"2 * 60 * 60",
+		`"a" + "b" == "ab"`,
+		"10 / (5 - 5)",
+		"1 + true",
+		"let x = if (false) { 1 }; x",
+		"if (true) { let a = 1; a }; a",
+		"if (false) { let a = 1; }; a",
+		"let f = fn() { return 1; 2 }; f()",
+		"let f = fn(n) { if (true) { return n * 2; }; n }; f(3)",
+		"let f = fn(n) { let a = 1; let g = fn() { a + n }; let b = [1, {2: 3}]; g() }; f(2)",
+		"let f = fn() { let a = 1 / 0; 1 }; f()",
+		"let f = fn(c) { if (c) { let a = 1; a + 1 } else { let b = 2; 3 } }; [f(true), f(false)]",
+		"let count = fn(n) { if (n == 0) { return 0; }; 1 + count(n - 1) }; count(2 * 5)",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			expected := evaluator.Eval(parse(t, input), object.NewEnvironment())
+
+			program := parse(t, input)
+			Optimize(program)
+			got := evaluator.Eval(program, object.NewEnvironment())
+
+			if diff := cmp.Diff(inspect(expected), inspect(got)); diff != "" {
+				t.Errorf("unexpected result\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestOptimizeResolvedProgram(t *testing.T) {
+	program := parse(t, "let f = fn() { let a = 1; let b = 2; b }; f()")
+	if !program.Resolved {
+		t.Fatal("program is not marked as resolved")
+	}
+
+	Optimize(program)
+	if !program.Resolved {
+		t.Error("optimized program is not marked as resolved")
+	}
+
+	fn := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if diff := cmp.Diff([]string{"b"}, fn.Locals); diff != "" {
+		t.Errorf("unexpected locals\n%s", diff)
+	}
+
+	got := evaluator.Eval(program, object.NewEnvironment())
+	if diff := cmp.Diff("2", inspect(got)); diff != "" {
+		t.Errorf("unexpected result\n%s", diff)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %q", p.Errors())
+	}
+	return program
+}
+
+func inspect(obj object.Object) string {
+	if obj == nil {
+		return "<nil>"
+	}
+	return obj.Inspect()
+}
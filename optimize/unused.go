@@ -0,0 +1,104 @@
+package optimize
+
+import "github.com/yagihash/monkey/ast"
+
+// removeUnusedLets removes the let statements of the body of fl, outside
+// nested function literals, that bind a pure value to a name no identifier
+// of fl uses. Only fl can see the variables its let statements bind, so
+// these are never looked up. Removing a statement can leave another variable
+// unused, so it repeats until there is none left to remove.
+//
+// The last statement of a block is kept, as it is the value of the block.
+func removeUnusedLets(fl *ast.FunctionLiteral) {
+	for {
+		used := usedNames(fl.Body)
+		if !removeLets(fl.Body, used) {
+			return
+		}
+	}
+}
+
+func removeLets(b *ast.BlockStatement, used map[string]bool) bool {
+	if b == nil {
+		return false
+	}
+
+	removed := false
+	stmts := b.Statements[:0]
+	for i, stmt := range b.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			if i != len(b.Statements)-1 && !used[stmt.Name.Value] && isPure(stmt.Value) {
+				removed = true
+				continue
+			}
+		case *ast.ExpressionStatement:
+			// Let statements in the branches of if expressions bind the
+			// variables of the function too.
+			if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+				if removeLets(ie.Consequence, used) {
+					removed = true
+				}
+				if removeLets(ie.Alternative, used) {
+					removed = true
+				}
+			}
+		}
+		stmts = append(stmts, stmt)
+	}
+	b.Statements = stmts
+
+	return removed
+}
+
+// usedNames returns the names of the variables the identifiers of body look
+// up, including those of nested function literals.
+func usedNames(body *ast.BlockStatement) map[string]bool {
+	used := make(map[string]bool)
+
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			used[n.Value] = true
+		case *ast.LetStatement:
+			ast.Inspect(n.Value, visit)
+			return false
+		case *ast.FunctionLiteral:
+			ast.Inspect(n.Body, visit)
+			return false
+		case *ast.MemberExpression:
+			// The property names an export of a module, not a variable.
+			ast.Inspect(n.Object, visit)
+			return false
+		}
+		return true
+	}
+	ast.Inspect(body, visit)
+
+	return used
+}
+
+// isPure reports whether evaluating exp cannot fail or have side effects.
+func isPure(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean, *ast.FunctionLiteral:
+		return true
+	case *ast.ArrayLiteral:
+		for _, el := range exp.Elements {
+			if !isPure(el) {
+				return false
+			}
+		}
+		return true
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			// Only constants are sure to be usable as hash keys.
+			if !isConstant(pair.Key) || !isPure(pair.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
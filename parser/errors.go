@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/yagihash/monkey/token"
+)
+
+// ErrorCode classifies a parse error.
+type ErrorCode string
+
+const (
+	// ErrUnexpectedToken is reported when a token other than the expected one
+	// follows.
+	ErrUnexpectedToken ErrorCode = "unexpected-token"
+	// ErrMissingExpression is reported when a token cannot start an
+	// expression.
+	ErrMissingExpression ErrorCode = "missing-expression"
+	// ErrInvalidLiteral is reported for number literals out of range.
+	ErrInvalidLiteral ErrorCode = "invalid-literal"
+)
+
+// Error is a parse error. Start and End span the offending token, which is
+// also kept as Found.
+type Error struct {
+	Code     ErrorCode
+	Start    token.Position
+	End      token.Position
+	Expected []token.TokenType
+	Found    token.Token
+	Message  string
+}
+
+// Error returns the message prefixed with the position it refers to.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Start, e.Message)
+}
+
+// Diagnostics returns the errors found while parsing, in source order.
+func (p *Parser) Diagnostics() []*Error {
+	return p.diagnostics
+}
+
+// Errors returns the messages of the errors found while parsing.
+func (p *Parser) Errors() []string {
+	errors := make([]string, 0, len(p.diagnostics))
+	for _, d := range p.diagnostics {
+		errors = append(errors, d.Message)
+	}
+	return errors
+}
+
+// report records an error at tok. Once a statement has an error, further
+// errors are dropped until the parser has synchronized, as they are mostly
+// caused by the first one.
+func (p *Parser) report(code ErrorCode, tok token.Token, expected []token.TokenType, msg string) {
+	if p.recovering {
+		return
+	}
+	p.recovering = true
+
+	p.diagnostics = append(p.diagnostics, &Error{
+		Code:     code,
+		Start:    tok.Pos,
+		End:      tok.End,
+		Expected: expected,
+		Found:    tok,
+		Message:  msg,
+	})
+}
+
+// synchronize skips the rest of a statement that failed to parse. It stops on
+// the semicolon ending the statement, or before a token that starts a new
+// statement or closes the enclosing block, so that the statement loop resumes
+// from a known state. It reports whether the current token is the closing
+// brace of the enclosing block, which the loop must not skip.
+func (p *Parser) synchronize() bool {
+	p.recovering = false
+
+	// A closing brace found where an expression was expected ends the
+	// enclosing block, even though the statement has already consumed it.
+	last := p.diagnostics[len(p.diagnostics)-1]
+	if last.Code == ErrMissingExpression && last.Found.Type == token.RBRACE {
+		return true
+	}
+
+	depth := 0
+	for !p.curTokenIs(token.EOF) {
+		switch p.curToken.Type {
+		case token.SEMICOLON:
+			if depth == 0 {
+				return false
+			}
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth > 0 {
+				depth--
+			}
+		}
+
+		if depth == 0 {
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.RBRACE, token.EOF:
+				return false
+			}
+		}
+
+		p.nextToken()
+	}
+
+	return false
+}
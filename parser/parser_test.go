@@ -9,6 +9,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 
 	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/token"
 )
 
 func TestParser_ParseProgram(t *testing.T) {
@@ -288,6 +289,12 @@ func TestParser_ParseProgram(t *testing.T) {
 			{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
 			{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
 			{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+			{"m.add(a, b)", "(m.add)(a, b)"},
+			{"1.5 * -2.25", "(1.5 * (-2.25))"},
+			{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
+			{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+			{"-m.x * m.y", "((-(m.x)) * (m.y))"},
+			{"import \"lib.monkey\".x", "(import \"lib.monkey\".x)"},
 		}
 
 		for _, c := range cases {
@@ -548,6 +555,193 @@ func TestParser_ParseProgram(t *testing.T) {
 		testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
 
 	})
+
+	t.Run("ImportExpression", func(t *testing.T) {
+		input := `let m = import "lib/math.monkey";`
+
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.LetStatement)
+		exp, ok := stmt.Value.(*ast.ImportExpression)
+		if !ok {
+			t.Fatalf("stmt.Value is not ast.ImportExpression. got=%T", stmt.Value)
+		}
+
+		if exp.Path.Value != "lib/math.monkey" {
+			t.Errorf("exp.Path.Value not %q. got=%q", "lib/math.monkey", exp.Path.Value)
+		}
+	})
+
+	t.Run("HashLiteral", func(t *testing.T) {
+		cases := []struct {
+			input string
+			want  string
+		}{
+			{`{}`, "{}"},
+			{`{"one": 1, "two": 2}`, `{one: 1, two: 2}`},
+			{`{"one": 0 + 1, true: 10 - 8, 3: 15 / 5}`, `{one: (0 + 1), true: (10 - 8), 3: (15 / 5)}`},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				l := lexer.New(c.input)
+				p := New(l)
+				program := p.ParseProgram()
+				checkParserErrors(t, p)
+
+				stmt := program.Statements[0].(*ast.ExpressionStatement)
+				hash, ok := stmt.Expression.(*ast.HashLiteral)
+				if !ok {
+					t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+				}
+
+				if diff := cmp.Diff(c.want, hash.String()); diff != "" {
+					t.Errorf("unexpected parsed string\n%s", diff)
+				}
+			})
+		}
+	})
+
+	t.Run("ImportWithoutPath", func(t *testing.T) {
+		l := lexer.New("import foo;")
+		p := New(l)
+		p.ParseProgram()
+
+		want := "expected next token to be STRING, got IDENT instead"
+		if len(p.Errors()) == 0 || p.Errors()[0] != want {
+			t.Errorf("unexpected errors. want first=%q, got=%q", want, p.Errors())
+		}
+	})
+
+	t.Run("ErrorRecovery", func(t *testing.T) {
+		cases := []struct {
+			input      string
+			want       []string
+			statements int
+		}{
+			{
+				input:      "let = 1; let x = 2;",
+				want:       []string{"expected next token to be IDENT, got = instead"},
+				statements: 1,
+			},
+			{
+				input: "let x = ; let y 2; x + y;",
+				want: []string{
+					"no prefix parse function for ; found",
+					"expected next token to be =, got INT instead",
+				},
+				statements: 2,
+			},
+			{
+				input:      "add(1, , 2); let y = 3;",
+				want:       []string{"no prefix parse function for , found"},
+				statements: 2,
+			},
+			{
+				input:      "if (x { 1 } let y = 3;",
+				want:       []string{"expected next token to be ), got { instead"},
+				statements: 2,
+			},
+			{
+				input:      "let f = fn(x) { let y = ; x }; f(1);",
+				want:       []string{"no prefix parse function for ; found"},
+				statements: 2,
+			},
+			{
+				input:      "let f = fn(x) { x + }; f(1);",
+				want:       []string{"no prefix parse function for } found"},
+				statements: 2,
+			},
+			{
+				input:      "let f = fn(x) { x",
+				want:       []string{"expected next token to be }, got EOF instead"},
+				statements: 1,
+			},
+			{
+				input:      "} 1;",
+				want:       []string{"no prefix parse function for } found"},
+				statements: 2,
+			},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				p := New(lexer.New(c.input))
+				program := p.ParseProgram()
+
+				if diff := cmp.Diff(c.want, p.Errors()); diff != "" {
+					t.Errorf("unexpected errors\n%s", diff)
+				}
+
+				if len(program.Statements) != c.statements {
+					t.Errorf("unexpected number of statements. want=%d, got=%d: %s", c.statements, len(program.Statements), program)
+				}
+			})
+		}
+	})
+
+	t.Run("Diagnostics", func(t *testing.T) {
+		p := New(lexer.New("let x = 1;\nlet y 2;\nlet z = 99999999999999999999;"))
+		p.ParseProgram()
+
+		want := []*Error{
+			{
+				Code:     ErrUnexpectedToken,
+				Start:    token.Position{Offset: 17, Line: 2, Column: 7},
+				End:      token.Position{Offset: 18, Line: 2, Column: 8},
+				Expected: []token.TokenType{token.ASSIGN},
+				Found: token.Token{
+					Type:    token.INT,
+					Literal: "2",
+					Pos:     token.Position{Offset: 17, Line: 2, Column: 7},
+					End:     token.Position{Offset: 18, Line: 2, Column: 8},
+				},
+				Message: "expected next token to be =, got INT instead",
+			},
+			{
+				Code:  ErrInvalidLiteral,
+				Start: token.Position{Offset: 28, Line: 3, Column: 9},
+				End:   token.Position{Offset: 48, Line: 3, Column: 29},
+				Found: token.Token{
+					Type:    token.INT,
+					Literal: "99999999999999999999",
+					Pos:     token.Position{Offset: 28, Line: 3, Column: 9},
+					End:     token.Position{Offset: 48, Line: 3, Column: 29},
+				},
+				Message: `could not parse "99999999999999999999" as integer`,
+			},
+		}
+
+		if diff := cmp.Diff(want, p.Diagnostics()); diff != "" {
+			t.Errorf("unexpected diagnostics\n%s", diff)
+		}
+
+		if got := p.Diagnostics()[0].Error(); got != "2:7: expected next token to be =, got INT instead" {
+			t.Errorf("unexpected error string. got=%q", got)
+		}
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		cases := []struct {
+			input    string
+			resolved bool
+		}{
+			{"let f = fn(x) { x };", true},
+			{"let f = fn(x) { x", false},
+		}
+
+		for _, c := range cases {
+			t.Run(c.input, func(t *testing.T) {
+				program := New(lexer.New(c.input)).ParseProgram()
+				if program.Resolved != c.resolved {
+					t.Errorf("unexpected resolved mark. want=%t, got=%t", c.resolved, program.Resolved)
+				}
+			})
+		}
+	})
 }
 
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
@@ -0,0 +1,175 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+)
+
+// WriteProfile writes the profile of the last Run to w in the gzipped
+// protocol buffer format read by go tool pprof. Its samples hold the number
+// of calls, the self time and the allocations of a function called through a
+// stack of monkey functions.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	var b protobuf
+	table := newStringTable()
+
+	valueType := func(typ, unit string) func(*protobuf) {
+		return func(b *protobuf) {
+			b.int64(1, table.index(typ))
+			b.int64(2, table.index(unit))
+		}
+	}
+
+	// Profile.sample_type
+	b.message(1, valueType("calls", "count"))
+	b.message(1, valueType("time", "nanoseconds"))
+	b.message(1, valueType("allocations", "count"))
+
+	// Profile.sample
+	for _, s := range p.order {
+		s := s
+		b.message(2, func(b *protobuf) {
+			b.packed(1, s.locations)
+			b.packedInt64(2, []int64{s.calls, int64(s.self), s.allocs})
+		})
+	}
+
+	// Profile.location
+	locations := make([]location, len(p.locations))
+	for loc, id := range p.locations {
+		locations[id-1] = loc
+	}
+	for i, loc := range locations {
+		id, loc := uint64(i+1), loc
+		b.message(4, func(b *protobuf) {
+			b.uint64(1, id)
+			b.message(4, func(b *protobuf) {
+				b.uint64(1, loc.fn.id)
+				b.int64(2, int64(loc.line))
+			})
+		})
+	}
+
+	// Profile.function
+	funcs := []*function{p.main}
+	for _, fn := range p.funcs {
+		funcs = append(funcs, fn)
+	}
+	sort.Slice(funcs, func(i, j int) bool { return funcs[i].id < funcs[j].id })
+	for _, fn := range funcs {
+		fn := fn
+		b.message(5, func(b *protobuf) {
+			b.uint64(1, fn.id)
+			b.int64(2, table.index(fn.Name))
+			b.int64(3, table.index(fn.Name))
+			b.int64(4, table.index(p.name))
+			b.int64(5, int64(fn.Line))
+		})
+	}
+
+	// Profile.time_nanos, duration_nanos, period_type, period and
+	// default_sample_type.
+	b.int64(9, p.start.UnixNano())
+	b.int64(10, int64(p.duration))
+	b.message(11, valueType("time", "nanoseconds"))
+	b.int64(12, 1)
+	b.int64(14, table.index("time"))
+
+	// Profile.string_table comes last, once every string has been indexed.
+	for _, s := range table.strings {
+		b.bytes(6, []byte(s))
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// stringTable numbers the strings of a profile. The empty string is always
+// the first.
+type stringTable struct {
+	strings []string
+	indices map[string]int64
+}
+
+func newStringTable() *stringTable {
+	return &stringTable{strings: []string{""}, indices: map[string]int64{"": 0}}
+}
+
+func (t *stringTable) index(s string) int64 {
+	if i, ok := t.indices[s]; ok {
+		return i
+	}
+
+	i := int64(len(t.strings))
+	t.strings = append(t.strings, s)
+	t.indices[s] = i
+
+	return i
+}
+
+// protobuf encodes the fields of a protocol buffer message. Fields with zero
+// values are left out, as proto3 does.
+type protobuf struct {
+	buf []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.buf = append(b.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	b.buf = append(b.buf, byte(x))
+}
+
+func (b *protobuf) key(field int, wire int) {
+	b.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (b *protobuf) uint64(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.key(field, wireVarint)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(field int, x int64) {
+	b.uint64(field, uint64(x))
+}
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.key(field, wireBytes)
+	b.varint(uint64(len(data)))
+	b.buf = append(b.buf, data...)
+}
+
+func (b *protobuf) packed(field int, xs []uint64) {
+	var p protobuf
+	for _, x := range xs {
+		p.varint(x)
+	}
+	b.bytes(field, p.buf)
+}
+
+func (b *protobuf) packedInt64(field int, xs []int64) {
+	var p protobuf
+	for _, x := range xs {
+		p.varint(uint64(x))
+	}
+	b.bytes(field, p.buf)
+}
+
+func (b *protobuf) message(field int, encode func(*protobuf)) {
+	var m protobuf
+	encode(&m)
+	b.bytes(field, m.buf)
+}
@@ -0,0 +1,265 @@
+// Package profiler measures where monkey programs spend their time. For every
+// function literal called, it records the number of calls, the time spent in
+// the function with and without its callees, and the values it allocated, and
+// writes them as a pprof profile.
+//
+// Calls to builtins are not measured separately: their time and allocations
+// belong to the function calling them.
+package profiler
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/object"
+)
+
+// Function holds the measurements of a function literal. Total is the time
+// from its outermost calls to their return, so recursive calls are not counted
+// twice, while Self excludes the time spent in other monkey functions. Allocs
+// counts the values allocated by the function itself.
+type Function struct {
+	Name   string
+	Line   int
+	Calls  int64
+	Total  time.Duration
+	Self   time.Duration
+	Allocs int64
+}
+
+// function is a function literal, or the top level of the program.
+type function struct {
+	Function
+	id     uint64
+	active int
+}
+
+// frame is a call in progress. line is the line of the call it is making.
+type frame struct {
+	fn    *function
+	line  int
+	start time.Time
+
+	allocs        uint64
+	children      time.Duration
+	childrenAlloc uint64
+}
+
+// sample holds the measurements of a function called through one stack.
+type sample struct {
+	locations []uint64
+	calls     int64
+	self      time.Duration
+	allocs    int64
+}
+
+// location is a line of a function.
+type location struct {
+	fn   *function
+	line int
+}
+
+// Profiler runs a program with an Evaluator reporting its calls to it. It is
+// not safe for concurrent use.
+type Profiler struct {
+	name      string
+	program   *ast.Program
+	evaluator *evaluator.Evaluator
+	now       func() time.Time
+
+	// names maps the bodies of the function literals bound by let statements
+	// to the names they are bound to.
+	names map[*ast.BlockStatement]string
+
+	funcs     map[*ast.BlockStatement]*function
+	main      *function
+	locations map[location]uint64
+	samples   map[string]*sample
+	order     []*sample
+
+	stack []*frame
+	key   []byte
+
+	start    time.Time
+	duration time.Duration
+}
+
+// New prepares program, read from the file called name, to be profiled by an
+// Evaluator created with opts.
+func New(name string, program *ast.Program, opts ...evaluator.Option) *Profiler {
+	p := &Profiler{
+		name:      name,
+		program:   program,
+		now:       time.Now,
+		names:     make(map[*ast.BlockStatement]string),
+		funcs:     make(map[*ast.BlockStatement]*function),
+		locations: make(map[location]uint64),
+		samples:   make(map[string]*sample),
+	}
+
+	p.evaluator = evaluator.New(append(opts, evaluator.WithHook(p))...)
+	p.main = &function{Function: Function{Name: "main", Line: 1}, id: 1}
+
+	ast.Inspect(program, func(n ast.Node) bool {
+		if let, ok := n.(*ast.LetStatement); ok {
+			if fn, ok := let.Value.(*ast.FunctionLiteral); ok {
+				p.names[fn.Body] = let.Name.Value
+			}
+		}
+		return true
+	})
+
+	return p
+}
+
+// Run evaluates the program in env and records its profile.
+func (p *Profiler) Run(env *object.Environment) object.Object {
+	p.start = p.now()
+	p.push(p.main, p.start)
+
+	result := p.evaluator.Eval(p.program, env)
+
+	end := p.now()
+	p.pop(end)
+	p.duration = end.Sub(p.start)
+
+	return result
+}
+
+// Functions returns the measurements of the top level of the program, named
+// "main", and of every function literal it called, by decreasing self time.
+func (p *Profiler) Functions() []Function {
+	funcs := []Function{p.main.Function}
+	for _, fn := range p.funcs {
+		funcs = append(funcs, fn.Function)
+	}
+
+	sort.Slice(funcs, func(i, j int) bool {
+		if funcs[i].Self != funcs[j].Self {
+			return funcs[i].Self > funcs[j].Self
+		}
+		if funcs[i].Line != funcs[j].Line {
+			return funcs[i].Line < funcs[j].Line
+		}
+		return funcs[i].Name < funcs[j].Name
+	})
+
+	return funcs
+}
+
+// Statement implements evaluator.Hook.
+func (p *Profiler) Statement(stmt ast.Statement, env *object.Environment) {}
+
+// Call implements evaluator.Hook.
+func (p *Profiler) Call(call *ast.CallExpression, fn object.Object, args []object.Object) {
+	f, ok := fn.(*object.Function)
+	if !ok {
+		return
+	}
+
+	p.stack[len(p.stack)-1].line = call.Token.Pos.Line
+	p.push(p.function(call, f), p.now())
+}
+
+// Return implements evaluator.Hook.
+func (p *Profiler) Return(call *ast.CallExpression, fn object.Object, result object.Object) {
+	if _, ok := fn.(*object.Function); !ok {
+		return
+	}
+
+	p.pop(p.now())
+}
+
+func (p *Profiler) function(call *ast.CallExpression, fn *object.Function) *function {
+	if f, ok := p.funcs[fn.Body]; ok {
+		return f
+	}
+
+	name, ok := p.names[fn.Body]
+	if !ok {
+		name = call.Function.String()
+	}
+
+	f := &function{
+		Function: Function{Name: name, Line: fn.Body.Token.Pos.Line},
+		id:       uint64(len(p.funcs) + 2),
+	}
+	p.funcs[fn.Body] = f
+
+	return f
+}
+
+func (p *Profiler) push(fn *function, now time.Time) {
+	fn.active++
+	p.stack = append(p.stack, &frame{fn: fn, start: now, allocs: p.evaluator.Allocations()})
+}
+
+func (p *Profiler) pop(now time.Time) {
+	top := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+
+	total := now.Sub(top.start)
+	allocs := p.evaluator.Allocations() - top.allocs
+	self := total - top.children
+	selfAllocs := int64(allocs - top.childrenAlloc)
+
+	fn := top.fn
+	fn.active--
+	fn.Calls++
+	fn.Self += self
+	fn.Allocs += selfAllocs
+	if fn.active == 0 {
+		fn.Total += total
+	}
+
+	s := p.sample(top)
+	s.calls++
+	s.self += self
+	s.allocs += selfAllocs
+
+	if len(p.stack) != 0 {
+		parent := p.stack[len(p.stack)-1]
+		parent.children += total
+		parent.childrenAlloc += allocs
+	}
+}
+
+// sample returns the sample of the stack from top to the bottom frame.
+func (p *Profiler) sample(top *frame) *sample {
+	locations := make([]uint64, 0, len(p.stack)+1)
+	locations = append(locations, p.location(top.fn, top.fn.Line))
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		locations = append(locations, p.location(p.stack[i].fn, p.stack[i].line))
+	}
+
+	p.key = p.key[:0]
+	for _, id := range locations {
+		p.key = strconv.AppendUint(p.key, id, 10)
+		p.key = append(p.key, ' ')
+	}
+
+	if s, ok := p.samples[string(p.key)]; ok {
+		return s
+	}
+
+	s := &sample{locations: locations}
+	p.samples[string(p.key)] = s
+	p.order = append(p.order, s)
+
+	return s
+}
+
+func (p *Profiler) location(fn *function, line int) uint64 {
+	loc := location{fn: fn, line: line}
+	if id, ok := p.locations[loc]; ok {
+		return id
+	}
+
+	id := uint64(len(p.locations) + 1)
+	p.locations[loc] = id
+
+	return id
+}
@@ -0,0 +1,137 @@
+package profiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+func newProfiler(t *testing.T, src string) *Profiler {
+	t.Helper()
+
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %q", p.Errors())
+	}
+
+	prof := New("test.monkey", program)
+
+	// Every reading of the clock advances it by a millisecond.
+	now := time.Unix(0, 0)
+	prof.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+
+	return prof
+}
+
+func TestProfiler(t *testing.T) {
+	ms := time.Millisecond
+
+	cases := []struct {
+		name  string
+		input string
+		want  []Function
+	}{
+		{
+			name: "Calls",
+			input: `let square = fn(x) { x * x };
+let sum = fn(a, b) { square(a) + square(b) };
+sum(1, 2)`,
+			want: []Function{
+				{Name: "sum", Line: 2, Calls: 1, Total: 5 * ms, Self: 3 * ms, Allocs: 1},
+				{Name: "main", Line: 1, Calls: 1, Total: 7 * ms, Self: 2 * ms, Allocs: 2},
+				{Name: "square", Line: 1, Calls: 2, Total: 2 * ms, Self: 2 * ms, Allocs: 2},
+			},
+		},
+		{
+			name: "Recursion",
+			input: `let fact = fn(n) {
+  if (n == 0) { 1 } else { n * fact(n - 1) }
+};
+fact(2)`,
+			want: []Function{
+				{Name: "fact", Line: 1, Calls: 3, Total: 5 * ms, Self: 5 * ms, Allocs: 3},
+				{Name: "main", Line: 1, Calls: 1, Total: 7 * ms, Self: 2 * ms, Allocs: 1},
+			},
+		},
+		{
+			name: "Anonymous",
+			input: `let apply = fn(f) { f(1) };
+apply(fn(x) { x })`,
+			// f(1) is a tail call, replacing the frame of apply.
+			want: []Function{
+				{Name: "main", Line: 1, Calls: 1, Total: 5 * ms, Self: 3 * ms, Allocs: 2},
+				{Name: "apply", Line: 1, Calls: 1, Total: 1 * ms, Self: 1 * ms, Allocs: 1},
+				{Name: "f", Line: 2, Calls: 1, Total: 1 * ms, Self: 1 * ms, Allocs: 1},
+			},
+		},
+		{
+			name: "TailRecursion",
+			input: `let loop = fn(n) {
+  if (n == 0) { 0 } else { loop(n - 1) }
+};
+loop(1000000)`,
+			want: []Function{
+				{Name: "main", Line: 1, Calls: 1, Total: 2000003 * ms, Self: 1000002 * ms, Allocs: 2},
+				{Name: "loop", Line: 1, Calls: 1000001, Total: 1000001 * ms, Self: 1000001 * ms, Allocs: 1998976},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := newProfiler(t, c.input)
+			if result := p.Run(object.NewEnvironment()); result.Type() == object.ErrObj {
+				t.Fatalf("program failed: %s", result.Inspect())
+			}
+
+			if diff := cmp.Diff(c.want, p.Functions()); diff != "" {
+				t.Errorf("unexpected functions\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteProfile(t *testing.T) {
+	p := newProfiler(t, `let f = fn() { 1 }; f(); f()`)
+	p.Run(object.NewEnvironment())
+
+	var buf bytes.Buffer
+	if err := p.WriteProfile(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The two calls of f share a sample: location 1 is f and location 2 the
+	// line of main calling it, so its values are 2 calls, 2ms and 2
+	// allocations.
+	sample := []byte{0x12, 0x0b, 0x0a, 0x02, 0x01, 0x02, 0x12, 0x05, 0x02, 0x80, 0x89, 0x7a, 0x02}
+	if !bytes.Contains(data, sample) {
+		t.Errorf("profile does not contain the sample of f: %x", data)
+	}
+
+	for _, s := range []string{"calls", "count", "time", "nanoseconds", "allocations", "main", "f", "test.monkey"} {
+		if !bytes.Contains(data, []byte(s)) {
+			t.Errorf("profile does not contain %q", s)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/token"
+)
+
+type command struct {
+	name  string
+	args  string
+	usage string
+	run   func(s *Session, arg string)
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"tokens", "<src>", "print the tokens of src", (*Session).commandTokens},
+		{"ast", "<src>", "print the syntax tree of src", (*Session).commandAST},
+		{"env", "", "list the bindings in the environment", (*Session).commandEnv},
+		{"type", "<expr>", "evaluate expr and print its type", (*Session).commandType},
+		{"load", "<file>", "evaluate file in the environment", (*Session).commandLoad},
+		{"reset", "", "discard all bindings and loaded modules", (*Session).commandReset},
+		{"help", "", "show this help", (*Session).commandHelp},
+	}
+}
+
+// runCommand runs a meta-command line such as ":env" or ":type 1 + 2".
+func (s *Session) runCommand(line string) {
+	name, arg := line[1:], ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, arg = name[:i], strings.TrimSpace(name[i+1:])
+	}
+
+	for _, c := range commands {
+		if c.name == name {
+			c.run(s, arg)
+			return
+		}
+	}
+
+	fmt.Fprintf(s.errOut, "unknown command: :%s (try :help)\n", name)
+}
+
+func (s *Session) commandTokens(src string) {
+	l := lexer.New(src)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		fmt.Fprintf(s.out, "%-9s %q\n", tok.Type, tok.Literal)
+	}
+}
+
+func (s *Session) commandAST(src string) {
+	program, ok := s.parse(src)
+	if !ok {
+		return
+	}
+	printTree(s.out, program, 0)
+}
+
+func (s *Session) commandEnv(string) {
+	seen := map[string]bool{}
+	for env := s.env; env != nil; env = env.Outer() {
+		for _, name := range env.Names() {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			val, _ := env.Get(name)
+			fmt.Fprintf(s.out, "%s: %s = %s\n", name, val.Type(), oneLine(val.Inspect()))
+		}
+	}
+}
+
+func (s *Session) commandType(src string) {
+	program, ok := s.parse(src)
+	if !ok {
+		return
+	}
+
+	evaluated := s.engine.Eval(program, s.env)
+	if evaluated == nil {
+		fmt.Fprintln(s.out, "no value")
+		return
+	}
+	fmt.Fprintln(s.out, evaluated.Type())
+}
+
+func (s *Session) commandLoad(file string) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(s.errOut, "could not load %s: %s\n", file, err)
+		return
+	}
+	s.eval(string(src))
+}
+
+func (s *Session) commandReset(string) {
+	s.reset()
+}
+
+func (s *Session) commandHelp(string) {
+	for _, c := range commands {
+		fmt.Fprintf(s.out, "  :%-15s %s\n", strings.TrimSpace(c.name+" "+c.args), c.usage)
+	}
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func printTree(out io.Writer, node ast.Node, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	switch node := node.(type) {
+	case *ast.Program:
+		fmt.Fprintf(out, "%sProgram\n", indent)
+		for _, stmt := range node.Statements {
+			printTree(out, stmt, depth+1)
+		}
+	case *ast.LetStatement:
+		fmt.Fprintf(out, "%sLetStatement %s\n", indent, node.Name.Value)
+		printTree(out, node.Value, depth+1)
+	case *ast.ReturnStatement:
+		fmt.Fprintf(out, "%sReturnStatement\n", indent)
+		printTree(out, node.ReturnValue, depth+1)
+	case *ast.ExpressionStatement:
+		fmt.Fprintf(out, "%sExpressionStatement\n", indent)
+		printTree(out, node.Expression, depth+1)
+	case *ast.BlockStatement:
+		fmt.Fprintf(out, "%sBlockStatement\n", indent)
+		for _, stmt := range node.Statements {
+			printTree(out, stmt, depth+1)
+		}
+	case *ast.Identifier:
+		fmt.Fprintf(out, "%sIdentifier %s\n", indent, node.Value)
+	case *ast.IntegerLiteral:
+		fmt.Fprintf(out, "%sIntegerLiteral %d\n", indent, node.Value)
+	case *ast.FloatLiteral:
+		fmt.Fprintf(out, "%sFloatLiteral %s\n", indent, node.Token.Literal)
+	case *ast.StringLiteral:
+		fmt.Fprintf(out, "%sStringLiteral %q\n", indent, node.Value)
+	case *ast.Boolean:
+		fmt.Fprintf(out, "%sBoolean %t\n", indent, node.Value)
+	case *ast.PrefixExpression:
+		fmt.Fprintf(out, "%sPrefixExpression %s\n", indent, node.Operator)
+		printTree(out, node.Right, depth+1)
+	case *ast.InfixExpression:
+		fmt.Fprintf(out, "%sInfixExpression %s\n", indent, node.Operator)
+		printTree(out, node.Left, depth+1)
+		printTree(out, node.Right, depth+1)
+	case *ast.IfExpression:
+		fmt.Fprintf(out, "%sIfExpression\n", indent)
+		printTree(out, node.Condition, depth+1)
+		printTree(out, node.Consequence, depth+1)
+		if node.Alternative != nil {
+			printTree(out, node.Alternative, depth+1)
+		}
+	case *ast.FunctionLiteral:
+		params := make([]string, len(node.Parameters))
+		for i, p := range node.Parameters {
+			params[i] = p.Value
+		}
+		fmt.Fprintf(out, "%sFunctionLiteral (%s)\n", indent, strings.Join(params, ", "))
+		printTree(out, node.Body, depth+1)
+	case *ast.CallExpression:
+		fmt.Fprintf(out, "%sCallExpression\n", indent)
+		printTree(out, node.Function, depth+1)
+		for _, arg := range node.Arguments {
+			printTree(out, arg, depth+1)
+		}
+	case *ast.ArrayLiteral:
+		fmt.Fprintf(out, "%sArrayLiteral\n", indent)
+		for _, el := range node.Elements {
+			printTree(out, el, depth+1)
+		}
+	case *ast.HashLiteral:
+		fmt.Fprintf(out, "%sHashLiteral\n", indent)
+		for _, pair := range node.Pairs {
+			printTree(out, pair.Key, depth+1)
+			printTree(out, pair.Value, depth+2)
+		}
+	case *ast.IndexExpression:
+		fmt.Fprintf(out, "%sIndexExpression\n", indent)
+		printTree(out, node.Left, depth+1)
+		printTree(out, node.Index, depth+1)
+	case *ast.ImportExpression:
+		fmt.Fprintf(out, "%sImportExpression %q\n", indent, node.Path.Value)
+	case *ast.MemberExpression:
+		fmt.Fprintf(out, "%sMemberExpression %s\n", indent, node.Property.Value)
+		printTree(out, node.Object, depth+1)
+	case nil:
+		fmt.Fprintf(out, "%s<nil>\n", indent)
+	default:
+		fmt.Fprintf(out, "%s%T\n", indent, node)
+	}
+}
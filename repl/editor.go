@@ -0,0 +1,250 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const maxHistory = 1000
+
+var errInterrupted = errors.New("interrupted")
+
+// editor reads lines from a terminal in raw mode, providing cursor movement,
+// history navigation and tab completion.
+type editor struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	history     []string
+	historyFile string
+
+	// complete returns the names that may complete the identifier being typed.
+	complete func() []string
+}
+
+func newEditor(in io.Reader, out io.Writer, historyFile string, complete func() []string) *editor {
+	ed := &editor{
+		in:          bufio.NewReader(in),
+		out:         out,
+		historyFile: historyFile,
+		complete:    complete,
+	}
+	ed.history = loadHistory(historyFile)
+	return ed
+}
+
+func (ed *editor) pending() bool {
+	return ed.in.Buffered() > 0
+}
+
+// readLine reads one line, including its trailing newline, the same way
+// bufio.Reader.ReadString('\n') does for plain input.
+func (ed *editor) readLine(prompt string) (string, error) {
+	line := []rune{}
+	pos := 0
+	historyPos := len(ed.history)
+	saved := ""
+
+	refresh := func() {
+		fmt.Fprintf(ed.out, "\r%s%s\x1b[K", prompt, string(line))
+		if back := len(line) - pos; back > 0 {
+			fmt.Fprintf(ed.out, "\x1b[%dD", back)
+		}
+	}
+
+	setLine := func(s string) {
+		line = []rune(s)
+		pos = len(line)
+	}
+
+	refresh()
+
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			return string(line), err
+		}
+
+		switch r {
+		case '\r', '\n':
+			pos = len(line)
+			refresh()
+			io.WriteString(ed.out, "\n")
+			ed.addHistory(string(line))
+			return string(line) + "\n", nil
+		case 3: // Ctrl-C
+			io.WriteString(ed.out, "^C\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(line) == 0 {
+				io.WriteString(ed.out, "\n")
+				return "", io.EOF
+			}
+			if pos < len(line) {
+				line = append(line[:pos], line[pos+1:]...)
+			}
+		case 1: // Ctrl-A
+			pos = 0
+		case 5: // Ctrl-E
+			pos = len(line)
+		case 2: // Ctrl-B
+			if pos > 0 {
+				pos--
+			}
+		case 6: // Ctrl-F
+			if pos < len(line) {
+				pos++
+			}
+		case 8, 127: // Backspace
+			if pos > 0 {
+				line = append(line[:pos-1], line[pos:]...)
+				pos--
+			}
+		case 11: // Ctrl-K
+			line = line[:pos]
+		case 21: // Ctrl-U
+			line = line[pos:]
+			pos = 0
+		case 23: // Ctrl-W
+			start := pos
+			for start > 0 && line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && line[start-1] != ' ' {
+				start--
+			}
+			line = append(line[:start], line[pos:]...)
+			pos = start
+		case '\t':
+			line, pos = ed.completeAt(prompt, line, pos)
+		case 27: // Escape sequence
+			switch ed.readEscape() {
+			case "[A", "OA":
+				if historyPos > 0 {
+					if historyPos == len(ed.history) {
+						saved = string(line)
+					}
+					historyPos--
+					setLine(ed.history[historyPos])
+				}
+			case "[B", "OB":
+				if historyPos < len(ed.history) {
+					historyPos++
+					if historyPos == len(ed.history) {
+						setLine(saved)
+					} else {
+						setLine(ed.history[historyPos])
+					}
+				}
+			case "[C", "OC":
+				if pos < len(line) {
+					pos++
+				}
+			case "[D", "OD":
+				if pos > 0 {
+					pos--
+				}
+			case "[H", "OH", "[1~", "[7~":
+				pos = 0
+			case "[F", "OF", "[4~", "[8~":
+				pos = len(line)
+			case "[3~":
+				if pos < len(line) {
+					line = append(line[:pos], line[pos+1:]...)
+				}
+			}
+		default:
+			if unicode.IsPrint(r) {
+				line = append(line[:pos], append([]rune{r}, line[pos:]...)...)
+				pos++
+			}
+		}
+
+		refresh()
+	}
+}
+
+// readEscape reads the rest of an ANSI escape sequence after ESC.
+func (ed *editor) readEscape() string {
+	var seq strings.Builder
+
+	r, _, err := ed.in.ReadRune()
+	if err != nil {
+		return ""
+	}
+	seq.WriteRune(r)
+
+	if r != '[' && r != 'O' {
+		return seq.String()
+	}
+
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			return seq.String()
+		}
+		seq.WriteRune(r)
+		if r >= 0x40 && r <= 0x7e {
+			return seq.String()
+		}
+	}
+}
+
+func (ed *editor) completeAt(prompt string, line []rune, pos int) ([]rune, int) {
+	start := pos
+	for start > 0 && isIdentRune(line[start-1]) {
+		start--
+	}
+
+	prefix := string(line[start:pos])
+	if prefix == "" || ed.complete == nil {
+		return line, pos
+	}
+
+	candidates := completions(prefix, ed.complete())
+	if len(candidates) == 0 {
+		return line, pos
+	}
+
+	common := longestCommonPrefix(candidates)
+	if len(candidates) > 1 && common == prefix {
+		fmt.Fprintf(ed.out, "\n%s\n", strings.Join(candidates, "  "))
+		return line, pos
+	}
+
+	insert := []rune(common[len(prefix):])
+	line = append(line[:pos], append(insert, line[pos:]...)...)
+	return line, pos + len(insert)
+}
+
+func completions(prefix string, names []string) []string {
+	seen := map[string]bool{}
+	var candidates []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+func longestCommonPrefix(strs []string) string {
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+func isIdentRune(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_'
+}
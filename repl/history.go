@@ -0,0 +1,94 @@
+package repl
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultHistoryFile returns the file the REPL keeps its history in, or "" if
+// the home directory cannot be determined.
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".monkey_history")
+}
+
+// loadHistory reads the last maxHistory lines of the history file name. As
+// the editor appends to the file, it trims it to these lines, so that it does
+// not grow beyond maxHistory and the lines of a session.
+func loadHistory(name string) []string {
+	if name == "" {
+		return nil
+	}
+
+	history, err := readHistory(name)
+	if err != nil {
+		return nil
+	}
+
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+		saveHistory(name, history)
+	}
+
+	return history
+}
+
+func readHistory(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			history = append(history, line)
+		}
+	}
+
+	return history, scanner.Err()
+}
+
+// saveHistory replaces the content of the history file name with history.
+func saveHistory(name string, history []string) error {
+	var b strings.Builder
+	for _, line := range history {
+		b.WriteString(line + "\n")
+	}
+
+	return os.WriteFile(name, []byte(b.String()), 0600)
+}
+
+func (ed *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if n := len(ed.history); n > 0 && ed.history[n-1] == line {
+		return
+	}
+
+	ed.history = append(ed.history, line)
+	if len(ed.history) > maxHistory {
+		ed.history = ed.history[len(ed.history)-maxHistory:]
+	}
+
+	if ed.historyFile == "" {
+		return
+	}
+
+	f, err := os.OpenFile(ed.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	f.WriteString(line + "\n")
+}
@@ -0,0 +1,60 @@
+package repl
+
+import (
+	"strings"
+
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/token"
+)
+
+// continuationTokens are the tokens that cannot end a complete program, so
+// input ending with one of them continues on the next line.
+var continuationTokens = map[token.TokenType]bool{
+	token.ASSIGN:   true,
+	token.PLUS:     true,
+	token.MINUS:    true,
+	token.ASTERISK: true,
+	token.SLASH:    true,
+	token.LT:       true,
+	token.GT:       true,
+	token.EQ:       true,
+	token.NOT_EQ:   true,
+	token.NOT:      true,
+	token.COMMA:    true,
+	token.DOT:      true,
+	token.COLON:    true,
+	token.LET:      true,
+	token.RETURN:   true,
+	token.IF:       true,
+	token.ELSE:     true,
+	token.FUNCTION: true,
+	token.IMPORT:   true,
+}
+
+// needsMoreInput reports whether src is an incomplete program: it has an
+// unterminated string, unbalanced brackets or a trailing operator.
+func needsMoreInput(src string) bool {
+	if strings.Count(src, `"`)%2 != 0 {
+		return true
+	}
+
+	l := lexer.New(src)
+	depth := 0
+	last := token.Token{Type: token.EOF}
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		switch tok.Type {
+		case token.LPAREN, token.LBRACE, token.LBRACKET:
+			depth++
+		case token.RPAREN, token.RBRACE, token.RBRACKET:
+			depth--
+		}
+		last = tok
+	}
+
+	if depth > 0 {
+		return true
+	}
+
+	return continuationTokens[last.Type]
+}
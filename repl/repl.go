@@ -2,47 +2,170 @@ package repl
 
 import (
 	"bufio"
-	"fmt"
 	"io"
+	"os"
+	"strings"
 
+	"github.com/yagihash/monkey/ast"
 	"github.com/yagihash/monkey/object"
 
-	"github.com/yagihash/monkey/evaluator"
-
 	"github.com/yagihash/monkey/parser"
 
 	"github.com/yagihash/monkey/lexer"
 )
 
-const PROMPT = ">> "
+const (
+	PROMPT              = ">> "
+	CONTINUATION_PROMPT = ".. "
+)
 
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	NewSession(WithInput(in), WithOutput(out), WithErrorOutput(out)).Run()
+}
+
+// Run reads and evaluates input until the input is exhausted.
+func (s *Session) Run() error {
+	if s.banner != "" {
+		if _, err := io.WriteString(s.out, s.banner); err != nil {
+			return err
+		}
+	}
+
+	lines := s.newLineReader()
 
+	var input strings.Builder
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
-			return
+		prompt := s.prompt
+		if input.Len() != 0 {
+			prompt = s.continuationPrompt
 		}
 
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+		line, err := lines.readLine(prompt)
+		if err == errInterrupted {
+			input.Reset()
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") && !needsMoreInput(input.String()) {
+			if strings.TrimSpace(input.String()) != "" {
+				s.eval(input.String())
+			}
+			input.Reset()
 
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+			s.runCommand(strings.TrimSpace(line))
+			if err != nil {
+				return ignoreEOF(err)
+			}
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+		input.WriteString(line)
+		if err != nil {
+			if strings.TrimSpace(input.String()) != "" {
+				s.eval(input.String())
+			}
+			return ignoreEOF(err)
+		}
+
+		// Keep reading while the input is incomplete or more of a paste is
+		// already waiting, so that multi-line programs run as one unit.
+		if needsMoreInput(input.String()) || lines.pending() {
+			continue
 		}
+
+		s.eval(input.String())
+		input.Reset()
+	}
+}
+
+func ignoreEOF(err error) error {
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+type lineReader interface {
+	readLine(prompt string) (string, error)
+	pending() bool
+}
+
+// newLineReader returns an editor when the input is a terminal and falls back
+// to plain line scanning otherwise, e.g. when input is piped.
+func (s *Session) newLineReader() lineReader {
+	if f, ok := s.in.(*os.File); ok && isTerminal(f.Fd()) {
+		return &terminalReader{
+			fd: f.Fd(),
+			ed: newEditor(f, s.out, s.historyFile, s.names),
+		}
+	}
+
+	return &plainReader{in: bufio.NewReader(s.in), out: s.out}
+}
+
+type plainReader struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func (r *plainReader) readLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+	return r.in.ReadString('\n')
+}
+
+func (r *plainReader) pending() bool {
+	return r.in.Buffered() > 0
+}
+
+type terminalReader struct {
+	fd uintptr
+	ed *editor
+}
+
+func (r *terminalReader) readLine(prompt string) (string, error) {
+	restore, err := makeRaw(r.fd)
+	if err != nil {
+		return "", err
 	}
+	defer restore()
+
+	return r.ed.readLine(prompt)
+}
+
+func (r *terminalReader) pending() bool {
+	return r.ed.pending()
+}
+
+func (s *Session) eval(input string) {
+	program, ok := s.parse(input)
+	if !ok {
+		return
+	}
+
+	evaluated := s.engine.Eval(program, s.env)
+	if evaluated == nil {
+		return
+	}
+
+	out := s.out
+	if evaluated.Type() == object.ErrObj {
+		out = s.errOut
+	}
+	io.WriteString(out, evaluated.Inspect())
+	io.WriteString(out, "\n")
+}
+
+func (s *Session) parse(input string) (*ast.Program, bool) {
+	l := lexer.New(input)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printParserErrors(s.errOut, p.Errors())
+		return nil, false
+	}
+
+	return program, true
 }
 
 func printParserErrors(out io.Writer, errors []string) {
@@ -0,0 +1,318 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/object"
+)
+
+func TestNeedsMoreInput(t *testing.T) {
+	cases := []struct {
+		input string
+		want  bool
+	}{
+		{"let x = 5;", false},
+		{"", false},
+		{"let add = fn(x, y) {", true},
+		{"let add = fn(x, y) {\n  x + y\n};", false},
+		{"add(1,", true},
+		{"add(1,\n 2)", false},
+		{"[1, 2", true},
+		{`{"a": 1`, true},
+		{"let x = 1 +", true},
+		{"let x =", true},
+		{"m.", true},
+		{`"unterminated`, true},
+		{`"{"`, false},
+		{"}", false},
+		{"if (x) { 1 } else", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			if got := needsMoreInput(c.input); got != c.want {
+				t.Errorf("needsMoreInput(%q) = %t, want %t", c.input, got, c.want)
+			}
+		})
+	}
+}
+
+func TestEditor(t *testing.T) {
+	names := func() []string {
+		return []string{"len", "let_me", "puts", "print", "println_count"}
+	}
+
+	cases := []struct {
+		name  string
+		keys  string
+		want  []string
+		setup []string
+	}{
+		{"Plain", "let x = 1;\r", []string{"let x = 1;\n"}, nil},
+		{"Backspace", "lex\x7ft\r", []string{"let\n"}, nil},
+		{"CursorMovement", "ac\x1b[Db\x1b[C!\r", []string{"abc!\n"}, nil},
+		{"HomeEnd", "bc\x01a\x05d\r", []string{"abcd\n"}, nil},
+		{"KillLine", "abc\x01\x0b\r", []string{"\n"}, nil},
+		{"KillWord", "let foo\x17bar\r", []string{"let bar\n"}, nil},
+		{"Delete", "abc\x01\x1b[3~\r", []string{"bc\n"}, nil},
+		{"UTF8", "日本\x1b[D語\r", []string{"日語本\n"}, nil},
+		{"HistoryUp", "\x1b[A\r", []string{"second\n"}, []string{"first", "second"}},
+		{"HistoryUpUp", "\x1b[A\x1b[A\r", []string{"first\n"}, []string{"first", "second"}},
+		{"HistoryDownRestores", "dra\x1b[A\x1b[Bft\r", []string{"draft\n"}, []string{"first"}},
+		{"CompleteUnique", "pu\t(1)\r", []string{"puts(1)\n"}, nil},
+		{"CompleteCommonPrefix", "pr\t\r", []string{"print\n"}, nil},
+		{"CompleteExtendsPrefix", "l\t\r", []string{"le\n"}, nil},
+		{"CompleteAmbiguous", "p\t\r", []string{"p\n"}, nil},
+		{"TwoLines", "a\rb\r", []string{"a\n", "b\n"}, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out strings.Builder
+			ed := newEditor(strings.NewReader(c.keys), &out, "", names)
+			ed.history = c.setup
+
+			for _, want := range c.want {
+				got, err := ed.readLine(">> ")
+				if err != nil {
+					t.Fatalf("readLine failed: %s", err)
+				}
+				if got != want {
+					t.Errorf("unexpected line. want=%q, got=%q", want, got)
+				}
+			}
+		})
+	}
+
+	t.Run("Interrupt", func(t *testing.T) {
+		ed := newEditor(strings.NewReader("abc\x03"), io.Discard, "", nil)
+		if _, err := ed.readLine(">> "); err != errInterrupted {
+			t.Errorf("expected errInterrupted. got=%v", err)
+		}
+	})
+
+	t.Run("EOF", func(t *testing.T) {
+		ed := newEditor(strings.NewReader("\x04"), io.Discard, "", nil)
+		if _, err := ed.readLine(">> "); err != io.EOF {
+			t.Errorf("expected io.EOF. got=%v", err)
+		}
+	})
+
+	t.Run("PersistentHistory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "history")
+
+		ed := newEditor(strings.NewReader("let a = 1;\r\rlet a = 1;\rputs(a)\r"), io.Discard, file, nil)
+		for i := 0; i < 4; i++ {
+			if _, err := ed.readLine(">> "); err != nil {
+				t.Fatalf("readLine failed: %s", err)
+			}
+		}
+
+		ed = newEditor(strings.NewReader("\x1b[A\x1b[A\r"), io.Discard, file, nil)
+		want := []string{"let a = 1;", "puts(a)"}
+		if diff := cmp.Diff(want, ed.history); diff != "" {
+			t.Errorf("unexpected history\n%s", diff)
+		}
+
+		got, _ := ed.readLine(">> ")
+		if got != "let a = 1;\n" {
+			t.Errorf("unexpected line. got=%q", got)
+		}
+	})
+
+	t.Run("TrimmedHistory", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "history")
+
+		var lines []string
+		for i := 0; i < maxHistory+10; i++ {
+			lines = append(lines, fmt.Sprintf("puts(%d)", i))
+		}
+		if err := os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		ed := newEditor(strings.NewReader("last\r"), io.Discard, file, nil)
+		if _, err := ed.readLine(">> "); err != nil {
+			t.Fatalf("readLine failed: %s", err)
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := strings.Join(append(lines[10:], "last"), "\n") + "\n"
+		if diff := cmp.Diff(want, string(data)); diff != "" {
+			t.Errorf("unexpected history file\n%s", diff)
+		}
+	})
+}
+
+func TestSession(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "lib.monkey")
+	if err := os.WriteFile(script, []byte("let double = fn(x) { x * 2 };\ndouble(21)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	host := object.NewEnvironment()
+	host.Set("answer", &object.Integer{Value: 42})
+
+	cases := []struct {
+		name    string
+		input   string
+		opts    []Option
+		wantOut string
+		wantErr string
+	}{
+		{
+			name:    "Prompts",
+			input:   "let f = fn(x) {\n  x\n};\n",
+			opts:    []Option{WithPrompt("> "), WithContinuationPrompt("| ")},
+			wantOut: "> | | > ",
+		},
+		{
+			name:    "Banner",
+			input:   "",
+			opts:    []Option{WithBanner("welcome\n")},
+			wantOut: "welcome\n",
+		},
+		{
+			name:    "MultiLine",
+			input:   "let add = fn(x, y) {\n  x + y\n};\nadd(1,\n  2)\n",
+			wantOut: "3\n",
+		},
+		{
+			name:    "Puts",
+			input:   `puts("hello")`,
+			wantOut: "hello\nnull\n",
+		},
+		{
+			name:    "Errors",
+			input:   "let x = ;\n:nope\nfoo\n",
+			wantErr: "\tno prefix parse function for ; found\nunknown command: :nope (try :help)\nERROR: identifier not found: foo\n",
+		},
+		{
+			name:    "Environment",
+			input:   "let y = answer + 1;\n:reset\nanswer\n:env\n",
+			opts:    []Option{WithEnvironment(host)},
+			wantOut: "42\nanswer: INTEGER = 42\n",
+		},
+		{
+			name:    "Engine",
+			input:   "1 + 2\n",
+			opts:    []Option{WithEngine(constantEngine{})},
+			wantOut: "constant\n",
+		},
+		{
+			name:    "Tokens",
+			input:   `:tokens let x = "a";`,
+			wantOut: "LET       \"let\"\nIDENT     \"x\"\n=         \"=\"\nSTRING    \"a\"\n;         \";\"\n",
+		},
+		{
+			name:  "AST",
+			input: ":ast let f = fn(x) { -x + 1 };",
+			wantOut: `Program
+  LetStatement f
+    FunctionLiteral (x)
+      BlockStatement
+        ExpressionStatement
+          InfixExpression +
+            PrefixExpression -
+              Identifier x
+            IntegerLiteral 1
+`,
+		},
+		{
+			name:    "ASTParseError",
+			input:   ":ast let = 1",
+			wantErr: "\texpected next token to be IDENT, got = instead\n",
+		},
+		{
+			name:    "Env",
+			input:   "let b = [1, 2];\nlet a = fn(x) {\n x\n};\n:env\n",
+			wantOut: "a: FUNCTION = fn(x) { x }\nb: ARRAY = [1, 2]\n",
+		},
+		{
+			name:    "Type",
+			input:   ":type 1.5 * 2\n:type let x = 1;\n",
+			wantOut: "FLOAT\nno value\n",
+		},
+		{
+			name:    "Load",
+			input:   ":load " + script + "\ndouble(2)\n",
+			wantOut: "42\n4\n",
+		},
+		{
+			name:    "LoadMissing",
+			input:   ":load " + filepath.Join(dir, "missing.monkey"),
+			wantErr: "could not load " + filepath.Join(dir, "missing.monkey") + ": open " + filepath.Join(dir, "missing.monkey") + ": no such file or directory\n",
+		},
+		{
+			name:    "Modules",
+			input:   "let lib = import \"lib.monkey\";\nlib.double(3)\n",
+			opts:    []Option{WithModules(os.DirFS(dir))},
+			wantOut: "6\n",
+		},
+		{
+			name:    "NoModules",
+			input:   "import \"lib.monkey\"\n",
+			wantErr: "ERROR: cannot import lib.monkey: no module loader\n",
+		},
+		{
+			name:    "Reset",
+			input:   "let x = 1;\n:reset\nx\n",
+			wantErr: "ERROR: identifier not found: x\n",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out, errOut strings.Builder
+			opts := []Option{
+				WithInput(strings.NewReader(c.input)),
+				WithOutput(&out),
+				WithErrorOutput(&errOut),
+				WithPrompt(""),
+				WithContinuationPrompt(""),
+			}
+
+			s := NewSession(append(opts, c.opts...)...)
+			if err := s.Run(); err != nil {
+				t.Fatalf("Run failed: %s", err)
+			}
+
+			if diff := cmp.Diff(c.wantOut, out.String()); diff != "" {
+				t.Errorf("unexpected output\n%s", diff)
+			}
+
+			if diff := cmp.Diff(c.wantErr, errOut.String()); diff != "" {
+				t.Errorf("unexpected error output\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Help", func(t *testing.T) {
+		var out strings.Builder
+		NewSession(WithInput(strings.NewReader(":help")), WithOutput(&out), WithPrompt("")).Run()
+
+		for _, c := range commands {
+			if !strings.Contains(out.String(), ":"+c.name) {
+				t.Errorf("help does not mention :%s\n%s", c.name, out.String())
+			}
+		}
+	})
+}
+
+type constantEngine struct{}
+
+func (constantEngine) Eval(node ast.Node, env *object.Environment) object.Object {
+	return &object.String{Value: "constant"}
+}
@@ -0,0 +1,162 @@
+package repl
+
+import (
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/object"
+)
+
+// Engine evaluates the programs entered in a Session. *evaluator.Evaluator
+// satisfies it.
+type Engine interface {
+	Eval(node ast.Node, env *object.Environment) object.Object
+}
+
+// Session is a REPL session. All of its output, including prompts, goes to
+// the writers it was configured with.
+type Session struct {
+	prompt             string
+	continuationPrompt string
+	banner             string
+	historyFile        string
+
+	in     io.Reader
+	out    io.Writer
+	errOut io.Writer
+
+	base    *object.Environment
+	env     *object.Environment
+	engine  Engine
+	modules fs.FS
+
+	// ownsEngine is set when the session created its engine itself and may
+	// replace it on :reset.
+	ownsEngine bool
+}
+
+type Option func(*Session)
+
+func WithPrompt(prompt string) Option {
+	return func(s *Session) {
+		s.prompt = prompt
+	}
+}
+
+func WithContinuationPrompt(prompt string) Option {
+	return func(s *Session) {
+		s.continuationPrompt = prompt
+	}
+}
+
+// WithBanner sets the text written to the output when the session starts.
+func WithBanner(banner string) Option {
+	return func(s *Session) {
+		s.banner = banner
+	}
+}
+
+func WithInput(in io.Reader) Option {
+	return func(s *Session) {
+		s.in = in
+	}
+}
+
+func WithOutput(out io.Writer) Option {
+	return func(s *Session) {
+		s.out = out
+	}
+}
+
+// WithErrorOutput sets the writer for parser errors and evaluation errors.
+func WithErrorOutput(errOut io.Writer) Option {
+	return func(s *Session) {
+		s.errOut = errOut
+	}
+}
+
+// WithEnvironment makes env visible to everything entered in the session.
+// Bindings made in the session are kept in an environment enclosed by env,
+// so :reset discards them without touching env itself.
+func WithEnvironment(env *object.Environment) Option {
+	return func(s *Session) {
+		s.base = env
+	}
+}
+
+func WithEngine(engine Engine) Option {
+	return func(s *Session) {
+		s.engine = engine
+	}
+}
+
+// WithModules makes the session import modules from fsys. Without it,
+// imports are errors. Modules are loaded again after :reset. It has no effect
+// with WithEngine.
+func WithModules(fsys fs.FS) Option {
+	return func(s *Session) {
+		s.modules = fsys
+	}
+}
+
+// WithHistoryFile sets the file the line editor keeps its history in. An
+// empty name disables persistent history.
+func WithHistoryFile(name string) Option {
+	return func(s *Session) {
+		s.historyFile = name
+	}
+}
+
+func NewSession(opts ...Option) *Session {
+	s := &Session{
+		prompt:             PROMPT,
+		continuationPrompt: CONTINUATION_PROMPT,
+		historyFile:        defaultHistoryFile(),
+		in:                 os.Stdin,
+		out:                os.Stdout,
+		errOut:             os.Stderr,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.ownsEngine = s.engine == nil
+	s.reset()
+
+	return s
+}
+
+func (s *Session) reset() {
+	if s.base != nil {
+		s.env = object.NewEnclosedEnvironment(s.base)
+	} else {
+		s.env = object.NewEnvironment()
+	}
+
+	if s.ownsEngine {
+		opts := []evaluator.Option{evaluator.WithStdout(s.out), evaluator.WithStderr(s.errOut)}
+		if s.modules != nil {
+			opts = append(opts, evaluator.WithModuleLoader(evaluator.NewModuleLoader(s.modules)))
+		}
+		s.engine = evaluator.New(opts...)
+	}
+}
+
+// names returns the names the line editor completes: every binding visible
+// from the session environment plus the builtins of the engine, if known.
+func (s *Session) names() []string {
+	var names []string
+	for env := s.env; env != nil; env = env.Outer() {
+		names = append(names, env.Names()...)
+	}
+
+	if b, ok := s.engine.(interface{ BuiltinNames() []string }); ok {
+		names = append(names, b.BuiltinNames()...)
+	}
+
+	return names
+}
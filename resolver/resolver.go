@@ -0,0 +1,169 @@
+// Package resolver assigns the identifiers of a program the place of their
+// variable, so that the evaluator can find local variables by index instead
+// of looking them up by name.
+//
+// Each function literal gets a slot for each of its parameters and for each
+// name bound by a let statement of its body, outside nested function
+// literals. An identifier naming one of the slots of the innermost function
+// binding its name is ScopeLocal, with the number of functions between them
+// as Depth; any other identifier is ScopeGlobal.
+//
+// Variables are still bound when their let statement runs: until then, the
+// slot is empty and the evaluator looks the name up in the enclosing
+// environments, as it would without the resolver.
+//
+// The resolver also marks the calls in tail position of function bodies,
+// which the evaluator makes without nesting, and the function literals
+// without nested ones, whose call environments the evaluator reuses.
+//
+// The parser resolves the programs it parses without errors; the evaluator
+// never writes to the programs it runs, so that they can be run
+// concurrently.
+package resolver
+
+import "github.com/yagihash/monkey/ast"
+
+type scope struct {
+	names []string
+	slots map[string]int
+}
+
+func (s *scope) declare(name string) {
+	if _, ok := s.slots[name]; ok {
+		return
+	}
+	s.slots[name] = len(s.names)
+	s.names = append(s.names, name)
+}
+
+type resolver struct {
+	scopes []*scope
+}
+
+// Resolve annotates the identifiers and function literals of program. It
+// does nothing for a program it has already resolved.
+func Resolve(program *ast.Program) {
+	if program.Resolved {
+		return
+	}
+
+	r := &resolver{}
+	for _, stmt := range program.Statements {
+		r.statement(stmt)
+	}
+
+	program.Resolved = true
+}
+
+func (r *resolver) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		r.expression(stmt.Value)
+		r.identifier(stmt.Name)
+	case *ast.ReturnStatement:
+		r.expression(stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		r.expression(stmt.Expression)
+	case *ast.BlockStatement:
+		r.block(stmt)
+	}
+}
+
+func (r *resolver) block(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+	for _, stmt := range block.Statements {
+		r.statement(stmt)
+	}
+}
+
+func (r *resolver) expression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		r.identifier(exp)
+	case *ast.PrefixExpression:
+		r.expression(exp.Right)
+	case *ast.InfixExpression:
+		r.expression(exp.Left)
+		r.expression(exp.Right)
+	case *ast.IfExpression:
+		r.expression(exp.Condition)
+		r.block(exp.Consequence)
+		r.block(exp.Alternative)
+	case *ast.FunctionLiteral:
+		r.function(exp)
+	case *ast.CallExpression:
+		// markTailCalls sets Tail again once the function body is resolved.
+		exp.Tail = false
+		r.expression(exp.Function)
+		for _, arg := range exp.Arguments {
+			r.expression(arg)
+		}
+	case *ast.MemberExpression:
+		// The property names an export of a module, not a variable.
+		r.expression(exp.Object)
+	case *ast.ArrayLiteral:
+		for _, el := range exp.Elements {
+			r.expression(el)
+		}
+	case *ast.IndexExpression:
+		r.expression(exp.Left)
+		r.expression(exp.Index)
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			r.expression(pair.Key)
+			r.expression(pair.Value)
+		}
+	}
+}
+
+func (r *resolver) function(fl *ast.FunctionLiteral) {
+	s := &scope{slots: make(map[string]int)}
+	for _, param := range fl.Parameters {
+		s.declare(param.Value)
+	}
+
+	fl.Leaf = true
+	ast.Inspect(fl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral:
+			fl.Leaf = false
+			return false
+		case *ast.LetStatement:
+			if n.Name != nil {
+				s.declare(n.Name.Value)
+			}
+		}
+		return true
+	})
+
+	fl.Locals = s.names
+
+	r.scopes = append(r.scopes, s)
+	for _, param := range fl.Parameters {
+		r.identifier(param)
+	}
+	r.block(fl.Body)
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	if fl.Body != nil {
+		markTailCalls(fl.Body, true)
+	}
+}
+
+func (r *resolver) identifier(id *ast.Identifier) {
+	if id == nil {
+		return
+	}
+
+	for depth := 0; depth < len(r.scopes); depth++ {
+		s := r.scopes[len(r.scopes)-1-depth]
+		if slot, ok := s.slots[id.Value]; ok {
+			id.Scope, id.Depth, id.Slot = ast.ScopeLocal, depth, slot
+			return
+		}
+	}
+
+	id.Scope, id.Depth, id.Slot = ast.ScopeGlobal, len(r.scopes), 0
+}
@@ -0,0 +1,135 @@
+package resolver_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/parser"
+	"github.com/yagihash/monkey/resolver"
+)
+
+func TestResolve(t *testing.T) {
+	input := `let a = 1;
+let f = fn(x, y, x) {
+  let z = x + a;
+  if (y) { let w = z; };
+  let g = fn(v) { v + z + w + len(a) };
+  g(m.z)
+};`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %q", p.Errors())
+	}
+
+	resolver.Resolve(program)
+	if !program.Resolved {
+		t.Error("program is not marked as resolved")
+	}
+
+	var identifiers, locals []string
+	var leaves []bool
+	ast.Inspect(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Identifier:
+			identifiers = append(identifiers, describe(n))
+		case *ast.FunctionLiteral:
+			locals = append(locals, fmt.Sprint(n.Locals))
+			leaves = append(leaves, n.Leaf)
+		}
+		return true
+	})
+
+	want := []string{
+		"a global 0",
+		"f global 0",
+		"x local 0.0",
+		"y local 0.1",
+		"x local 0.0",
+		"z local 0.2",
+		"x local 0.0",
+		"a global 1",
+		"y local 0.1",
+		"w local 0.3",
+		"z local 0.2",
+		"g local 0.4",
+		"v local 0.0",
+		"v local 0.0",
+		"z local 1.2",
+		"w local 1.3",
+		"len global 2",
+		"a global 2",
+		"g local 0.4",
+		"m global 1",
+		"z unresolved",
+	}
+	if diff := cmp.Diff(want, identifiers); diff != "" {
+		t.Errorf("unexpected identifiers\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"[x y z w g]", "[v]"}, locals); diff != "" {
+		t.Errorf("unexpected locals\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]bool{false, true}, leaves); diff != "" {
+		t.Errorf("unexpected leaves\n%s", diff)
+	}
+}
+
+func TestResolveTailCalls(t *testing.T) {
+	input := `let f = fn(n) {
+  if (n > 0) { return a(n); };
+  b(n);
+  let x = c(n);
+  if (n == 0) { d(n) } else { e(n) + f(n) }
+};
+g(h(1));
+let k = fn() { fn(m) { i(m) }(1) };`
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parse errors: %q", p.Errors())
+	}
+
+	tails := func() []string {
+		var calls []string
+		ast.Inspect(program, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpression); ok && call.Tail {
+				calls = append(calls, call.Function.String())
+			}
+			return true
+		})
+		return calls
+	}
+
+	resolver.Resolve(program)
+	if diff := cmp.Diff([]string{"a", "d", "fn(m) i(m)", "i"}, tails()); diff != "" {
+		t.Errorf("unexpected tail calls\n%s", diff)
+	}
+
+	// Calls moved out of tail position lose their mark once resolved again.
+	body := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral).Body
+	body.Statements = body.Statements[1:2]
+	program.Resolved = false
+	resolver.Resolve(program)
+	if diff := cmp.Diff([]string{"b", "fn(m) i(m)", "i"}, tails()); diff != "" {
+		t.Errorf("unexpected tail calls after resolving again\n%s", diff)
+	}
+}
+
+func describe(id *ast.Identifier) string {
+	switch id.Scope {
+	case ast.ScopeLocal:
+		return fmt.Sprintf("%s local %d.%d", id.Value, id.Depth, id.Slot)
+	case ast.ScopeGlobal:
+		return fmt.Sprintf("%s global %d", id.Value, id.Depth)
+	default:
+		return id.Value + " unresolved"
+	}
+}
@@ -0,0 +1,40 @@
+package resolver
+
+import "github.com/yagihash/monkey/ast"
+
+// markTailCalls marks the calls in tail position of block, a function body
+// or a block within one. A call is in tail position when it is the value of a
+// return statement, or the last expression of the function body, where an
+// if expression passes the tail position on to the last expressions of its
+// branches. When tail is false, block does not give the function its value,
+// so only its return statements hold tail calls.
+func markTailCalls(block *ast.BlockStatement, tail bool) {
+	for i, stmt := range block.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.ReturnStatement:
+			markTailExpression(stmt.ReturnValue)
+		case *ast.ExpressionStatement:
+			if tail && i == len(block.Statements)-1 {
+				markTailExpression(stmt.Expression)
+			} else if ie, ok := stmt.Expression.(*ast.IfExpression); ok {
+				markTailBranches(ie, false)
+			}
+		}
+	}
+}
+
+func markTailExpression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.CallExpression:
+		exp.Tail = true
+	case *ast.IfExpression:
+		markTailBranches(exp, true)
+	}
+}
+
+func markTailBranches(ie *ast.IfExpression, tail bool) {
+	markTailCalls(ie.Consequence, tail)
+	if ie.Alternative != nil {
+		markTailCalls(ie.Alternative, tail)
+	}
+}
@@ -0,0 +1,182 @@
+package testrunner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Summary counts the results of a test run.
+type Summary struct {
+	Tests    int
+	Passed   int
+	Failed   int
+	Errors   int
+	Duration time.Duration
+}
+
+// Summarize counts results.
+func Summarize(results []Result) Summary {
+	var s Summary
+	for _, r := range results {
+		s.Tests++
+		s.Duration += r.Duration
+		switch r.Status {
+		case StatusPass:
+			s.Passed++
+		case StatusFail:
+			s.Failed++
+		case StatusError:
+			s.Errors++
+		}
+	}
+	return s
+}
+
+// OK reports whether every test passed.
+func (s Summary) OK() bool {
+	return s.Failed == 0 && s.Errors == 0
+}
+
+// WriteText writes results to w for people to read: a line for each test,
+// followed by the message of those that did not pass, and a summary.
+func WriteText(w io.Writer, results []Result) error {
+	var b strings.Builder
+
+	for _, r := range results {
+		name := r.File
+		if r.Name != "" {
+			name += ": " + r.Name
+		}
+		fmt.Fprintf(&b, "%-5s %s (%s)\n", strings.ToUpper(string(r.Status)), name, seconds(r.Duration))
+
+		if r.Message != "" {
+			for _, line := range strings.Split(r.Message, "\n") {
+				fmt.Fprintf(&b, "      %s\n", line)
+			}
+		}
+	}
+
+	s := Summarize(results)
+	fmt.Fprintf(&b, "%d tests, %d passed, %d failed, %d errors (%s)\n", s.Tests, s.Passed, s.Failed, s.Errors, seconds(s.Duration))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes results to w as JUnit XML, with a test suite for each
+// file.
+func WriteJUnit(w io.Writer, results []Result) error {
+	var files []string
+	byFile := make(map[string][]Result)
+	for _, r := range results {
+		if _, ok := byFile[r.File]; !ok {
+			files = append(files, r.File)
+		}
+		byFile[r.File] = append(byFile[r.File], r)
+	}
+
+	var suites []junitSuite
+	for _, file := range files {
+		s := Summarize(byFile[file])
+		suite := junitSuite{
+			Name:     file,
+			Tests:    s.Tests,
+			Failures: s.Failed,
+			Errors:   s.Errors,
+			Time:     junitTime(s.Duration),
+		}
+		for _, r := range byFile[file] {
+			suite.Cases = append(suite.Cases, junitTestCase(r))
+		}
+		suites = append(suites, suite)
+	}
+
+	s := Summarize(results)
+	doc := junitSuites{
+		Tests:    s.Tests,
+		Failures: s.Failed,
+		Errors:   s.Errors,
+		Time:     junitTime(s.Duration),
+		Suites:   suites,
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTestCase(r Result) junitCase {
+	name := r.Name
+	if name == "" {
+		name = r.File
+	}
+	c := junitCase{Name: name, Classname: r.File, Time: junitTime(r.Duration)}
+
+	problem := &junitProblem{Message: firstLine(r.Message), Text: r.Message}
+	switch r.Status {
+	case StatusFail:
+		c.Failure = problem
+	case StatusError:
+		c.Error = problem
+	}
+
+	return c
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
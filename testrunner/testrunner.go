@@ -0,0 +1,207 @@
+// Package testrunner runs the tests written in monkey. Tests live in files
+// named *_test.monkey: every top-level let statement binding a name starting
+// with "test_" defines a test, which passes when calling its function without
+// arguments does not fail.
+//
+// Each test runs in a fresh environment and Evaluator: the whole file is
+// evaluated again before the test function is called, so tests cannot see the
+// bindings made by the others.
+package testrunner
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yagihash/monkey/ast"
+	"github.com/yagihash/monkey/evaluator"
+	"github.com/yagihash/monkey/lexer"
+	"github.com/yagihash/monkey/object"
+	"github.com/yagihash/monkey/parser"
+)
+
+// Status is the outcome of a test.
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	// StatusFail is the status of tests whose assertions failed.
+	StatusFail Status = "fail"
+	// StatusError is the status of tests that failed in another way, or of
+	// files that could not be parsed.
+	StatusError Status = "error"
+)
+
+// Result is the outcome of the test Name of File. A Result for a file that
+// could not be parsed has no Name.
+type Result struct {
+	File     string
+	Name     string
+	Status   Status
+	Message  string
+	Duration time.Duration
+}
+
+// Runner runs the tests of a file system.
+type Runner struct {
+	fsys   fs.FS
+	filter *regexp.Regexp
+	opts   []evaluator.Option
+	now    func() time.Time
+}
+
+// Option configures a Runner created by New.
+type Option func(*Runner)
+
+// WithFilter makes the Runner run only the tests whose names match re.
+func WithFilter(re *regexp.Regexp) Option {
+	return func(r *Runner) {
+		r.filter = re
+	}
+}
+
+// WithEvaluatorOptions creates the Evaluators running the tests with opts.
+func WithEvaluatorOptions(opts ...evaluator.Option) Option {
+	return func(r *Runner) {
+		r.opts = append(r.opts, opts...)
+	}
+}
+
+// New returns a Runner for the tests in fsys. Tests import modules relative to
+// the directory of their file.
+func New(fsys fs.FS, opts ...Option) *Runner {
+	r := &Runner{fsys: fsys, now: time.Now}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Discover returns the paths of the test files in the Runner's file system,
+// in lexical order.
+func (r *Runner) Discover() ([]string, error) {
+	var files []string
+
+	err := fs.WalkDir(r.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(p, "_test.monkey") {
+			files = append(files, p)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// Run runs the tests of every file found by Discover.
+func (r *Runner) Run() ([]Result, error) {
+	files, err := r.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, file := range files {
+		fileResults, err := r.RunFile(file)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, fileResults...)
+	}
+
+	return results, nil
+}
+
+// RunFile runs the tests of the file at path p, in the order they are defined.
+func (r *Runner) RunFile(p string) ([]Result, error) {
+	src, err := fs.ReadFile(r.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := parser.New(lexer.New(string(src)))
+	program := ps.ParseProgram()
+	if len(ps.Diagnostics()) != 0 {
+		var messages []string
+		for _, d := range ps.Diagnostics() {
+			messages = append(messages, p+":"+d.Error())
+		}
+		return []Result{{File: p, Status: StatusError, Message: strings.Join(messages, "\n")}}, nil
+	}
+
+	var results []Result
+	for _, name := range testNames(program) {
+		if r.filter != nil && !r.filter.MatchString(name) {
+			continue
+		}
+
+		results = append(results, r.runTest(p, program, name))
+	}
+
+	return results, nil
+}
+
+func testNames(program *ast.Program) []string {
+	var names []string
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if ok && strings.HasPrefix(let.Name.Value, "test_") {
+			names = append(names, let.Name.Value)
+		}
+	}
+	return names
+}
+
+func (r *Runner) runTest(p string, program *ast.Program, name string) Result {
+	dir, err := fs.Sub(r.fsys, path.Dir(p))
+	if err != nil {
+		return Result{File: p, Name: name, Status: StatusError, Message: err.Error()}
+	}
+
+	opts := append([]evaluator.Option{evaluator.WithModuleLoader(evaluator.NewModuleLoader(dir))}, r.opts...)
+	e := evaluator.New(opts...)
+	env := object.NewEnvironment()
+
+	start := r.now()
+	result := e.Eval(program, env)
+	if !isError(result) {
+		result = r.call(e, env, name)
+	}
+	res := Result{File: p, Name: name, Status: StatusPass, Duration: r.now().Sub(start)}
+
+	if err, ok := result.(*object.Error); ok {
+		res.Status = StatusError
+		res.Message = err.Message
+		if err.Assertion {
+			res.Status = StatusFail
+			res.Message = p + ":" + err.Message
+		}
+	}
+
+	return res
+}
+
+// call calls the test function bound to name in env.
+func (r *Runner) call(e *evaluator.Evaluator, env *object.Environment, name string) object.Object {
+	obj, _ := env.Get(name)
+	fn, ok := obj.(*object.Function)
+	if !ok {
+		return &object.Error{Message: name + " is not a function"}
+	}
+	if len(fn.Parameters) != 0 {
+		return &object.Error{Message: name + " must not take parameters"}
+	}
+
+	call := &ast.CallExpression{Function: &ast.Identifier{Value: name}}
+	return e.Eval(call, env)
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ErrObj
+}
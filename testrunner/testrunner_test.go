@@ -0,0 +1,157 @@
+package testrunner
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/yagihash/monkey/evaluator"
+)
+
+var fsys = fstest.MapFS{
+	"math_test.monkey": {Data: []byte(`let lib = import "./lib/add.monkey";
+let calls = 0;
+
+let test_add = fn() {
+  assert_eq(lib.add(1, 2), 3);
+};
+
+let test_negative = fn() {
+  assert_eq(lib.add(1, -2), 1, "adding a negative number");
+};
+
+let test_isolated = fn() {
+  let calls = calls + 1;
+  assert_eq(calls, 1);
+};
+
+let test_missing = fn() { missing(1) };
+let test_value = 1;
+let helper = fn() { 1 };
+`)},
+	"lib/add.monkey":         {Data: []byte("let add = fn(a, b) { a + b };\n")},
+	"lib/broken_test.monkey": {Data: []byte("let test_x = fn() { let = 1 };\n")},
+	"lib/notes.txt":          {Data: []byte("not a test\n")},
+}
+
+func newRunner(opts ...Option) *Runner {
+	r := New(fsys, append(opts, WithEvaluatorOptions(evaluator.WithStdout(io.Discard)))...)
+
+	// Every reading of the clock advances it by a millisecond, so every test
+	// takes one.
+	now := time.Unix(0, 0)
+	r.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+
+	return r
+}
+
+func TestRunner(t *testing.T) {
+	ms := time.Millisecond
+
+	t.Run("Discover", func(t *testing.T) {
+		files, err := newRunner().Discover()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{"lib/broken_test.monkey", "math_test.monkey"}
+		if diff := cmp.Diff(want, files); diff != "" {
+			t.Errorf("unexpected files\n%s", diff)
+		}
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		results, err := newRunner().Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []Result{
+			{File: "lib/broken_test.monkey", Status: StatusError, Message: "lib/broken_test.monkey:1:25: expected next token to be IDENT, got = instead"},
+			{File: "math_test.monkey", Name: "test_add", Status: StatusPass, Duration: ms},
+			{File: "math_test.monkey", Name: "test_negative", Status: StatusFail, Message: "math_test.monkey:9:3: assert_eq failed: got -1, want 1: adding a negative number", Duration: ms},
+			{File: "math_test.monkey", Name: "test_isolated", Status: StatusPass, Duration: ms},
+			{File: "math_test.monkey", Name: "test_missing", Status: StatusError, Message: "identifier not found: missing", Duration: ms},
+			{File: "math_test.monkey", Name: "test_value", Status: StatusError, Message: "test_value is not a function", Duration: ms},
+		}
+		if diff := cmp.Diff(want, results); diff != "" {
+			t.Errorf("unexpected results\n%s", diff)
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		results, err := newRunner(WithFilter(regexp.MustCompile("^test_(add|isolated)$"))).Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, r := range results {
+			names = append(names, r.File+": "+r.Name)
+		}
+
+		want := []string{"lib/broken_test.monkey: ", "math_test.monkey: test_add", "math_test.monkey: test_isolated"}
+		if diff := cmp.Diff(want, names); diff != "" {
+			t.Errorf("unexpected tests\n%s", diff)
+		}
+	})
+}
+
+var results = []Result{
+	{File: "a_test.monkey", Name: "test_one", Status: StatusPass, Duration: 1500 * time.Microsecond},
+	{File: "a_test.monkey", Name: "test_two", Status: StatusFail, Message: "a_test.monkey:2:3: assert failed: got false", Duration: time.Millisecond},
+	{File: "b_test.monkey", Status: StatusError, Message: "b_test.monkey:1:5: first\nb_test.monkey:2:5: second"},
+}
+
+func TestWriteText(t *testing.T) {
+	var out strings.Builder
+	if err := WriteText(&out, results); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `PASS  a_test.monkey: test_one (0.002s)
+FAIL  a_test.monkey: test_two (0.001s)
+      a_test.monkey:2:3: assert failed: got false
+ERROR b_test.monkey (0.000s)
+      b_test.monkey:1:5: first
+      b_test.monkey:2:5: second
+3 tests, 1 passed, 1 failed, 1 errors (0.003s)
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("unexpected output\n%s", diff)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out strings.Builder
+	if err := WriteJUnit(&out, results); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="1" errors="1" time="0.003">
+  <testsuite name="a_test.monkey" tests="2" failures="1" errors="0" time="0.003">
+    <testcase name="test_one" classname="a_test.monkey" time="0.002"></testcase>
+    <testcase name="test_two" classname="a_test.monkey" time="0.001">
+      <failure message="a_test.monkey:2:3: assert failed: got false">a_test.monkey:2:3: assert failed: got false</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="b_test.monkey" tests="1" failures="0" errors="1" time="0.000">
+    <testcase name="b_test.monkey" classname="b_test.monkey" time="0.000">
+      <error message="b_test.monkey:1:5: first">b_test.monkey:1:5: first&#xA;b_test.monkey:2:5: second</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if diff := cmp.Diff(want, out.String()); diff != "" {
+		t.Errorf("unexpected output\n%s", diff)
+	}
+}
@@ -1,8 +1,27 @@
 package token
 
+import "fmt"
+
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// Pos is the position of the first character of the token and End the
+	// position just after its last one.
+	Pos Position
+	End Position
+}
+
+// Position is a location in the source. Line and Column start at 1 and Column
+// counts bytes; Offset is the byte offset from the start of the input.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
 }
 
 type TokenType string
@@ -13,6 +32,7 @@ const (
 	EOF       = "EOF"
 	IDENT     = "IDENT"
 	INT       = "INT"
+	FLOAT     = "FLOAT"
 	STRING    = "STRING"
 	ASSIGN    = "="
 	PLUS      = "+"
@@ -20,16 +40,20 @@ const (
 	ASTERISK  = "*"
 	SLASH     = "/"
 	COMMA     = ","
+	DOT       = "."
 	LPAREN    = "("
 	RPAREN    = ")"
 	LBRACE    = "{"
 	RBRACE    = "}"
+	LBRACKET  = "["
+	RBRACKET  = "]"
 	NOT       = "!"
 	LT        = "<"
 	GT        = ">"
 	EQ        = "=="
 	NOT_EQ    = "!="
 	SEMICOLON = ";"
+	COLON     = ":"
 	FUNCTION  = "FUNCTION"
 	RETURN    = "RETURN"
 	LET       = "LET"
@@ -37,6 +61,7 @@ const (
 	FALSE     = "FALSE"
 	IF        = "IF"
 	ELSE      = "ELSE"
+	IMPORT    = "IMPORT"
 )
 
 var keywords = map[string]TokenType{
@@ -47,6 +72,7 @@ var keywords = map[string]TokenType{
 	"false":  FALSE,
 	"if":     IF,
 	"else":   ELSE,
+	"import": IMPORT,
 }
 
 func LookupIdent(ident string) TokenType {